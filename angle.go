@@ -0,0 +1,143 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// AngleMode selects the unit the trigonometric builtins take their arguments
+// in and the inverse ones return their results in.
+type AngleMode byte
+
+const (
+	Radians AngleMode = iota
+	Degrees
+	Gradians
+)
+
+var angle_mode_map = map[AngleMode]string{
+	Radians:  "rad",
+	Degrees:  "deg",
+	Gradians: "grad",
+}
+
+func (mode AngleMode) String() string {
+	if name, ok := angle_mode_map[mode]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("%d", mode)
+}
+
+func ParseAngleMode(name string) (AngleMode, error) {
+	for mode, mode_name := range angle_mode_map {
+		if mode_name == name {
+			return mode, nil
+		}
+	}
+
+	return Radians, fmt.Errorf("unknown angle mode '%s'", name)
+}
+
+// turn is a full turn in the unit of the angle mode.
+func (mode AngleMode) turn() float64 {
+	switch mode {
+	case Degrees:
+		return 360
+	case Gradians:
+		return 400
+	}
+
+	return 2 * math.Pi
+}
+
+// to_radians converts an angle in the angle mode to radians.
+func (ctx *CallContext) to_radians(x float64) float64 {
+	if ctx.Angle == Radians {
+		return x
+	}
+
+	return x / ctx.Angle.turn() * (2 * math.Pi)
+}
+
+// from_radians converts an angle in radians to the angle mode.
+func (ctx *CallContext) from_radians(x float64) float64 {
+	if ctx.Angle == Radians {
+		return x
+	}
+
+	return x / (2 * math.Pi) * ctx.Angle.turn()
+}
+
+func (ctx *CallContext) complex_to_radians(z complex128) complex128 {
+	return z * complex(ctx.to_radians(1), 0)
+}
+
+func (ctx *CallContext) complex_from_radians(z complex128) complex128 {
+	return z * complex(ctx.from_radians(1), 0)
+}
+
+// to_degrees converts an angle in the angle mode to degrees.
+func (ctx *CallContext) to_degrees(x float64) float64 {
+	return x * 360 / ctx.Angle.turn()
+}
+
+// angle_sin, angle_cos and angle_tan are the trigonometric functions of an
+// angle in the angle mode. Degrees and gradians go through sin_deg, so sin(180)
+// is 0 in Degrees rather than the 1.2e-16 of sin(pi).
+func (ctx *CallContext) angle_sin(x float64) float64 {
+	if ctx.Angle == Radians {
+		return math.Sin(x)
+	}
+
+	return sin_deg(ctx.to_degrees(x))
+}
+
+func (ctx *CallContext) angle_cos(x float64) float64 {
+	if ctx.Angle == Radians {
+		return math.Cos(x)
+	}
+
+	return cos_deg(ctx.to_degrees(x))
+}
+
+func (ctx *CallContext) angle_tan(x float64) float64 {
+	if ctx.Angle == Radians {
+		return math.Tan(x)
+	}
+
+	return sin_deg(ctx.to_degrees(x)) / cos_deg(ctx.to_degrees(x))
+}
+
+// big_sin_cos is the big.Float counterpart of angle_sin and angle_cos, it
+// returns nils for angles it cannot reduce.
+func (ctx *CallContext) big_sin_cos(x *big.Float) (*big.Float, *big.Float) {
+	if ctx.Angle == Radians {
+		return big_sin_cos(x, ctx.Precision)
+	}
+
+	if x.IsInf() || x.MantExp(nil) > max_reduced_exp {
+		return nil, nil
+	}
+
+	degrees, _ := x.Rat(nil)
+	degrees.Mul(degrees, big.NewRat(360, int64(ctx.Angle.turn())))
+
+	return big_sin_cos_degrees(degrees, ctx.Precision)
+}
+
+// big_from_radians converts an angle in radians to the angle mode, passing
+// nil through.
+func (ctx *CallContext) big_from_radians(x *big.Float) *big.Float {
+	if x == nil || ctx.Angle == Radians {
+		return x
+	}
+
+	wp := ctx.Precision + guard_bits
+	turn := big_pi(wp)
+	turn.SetMantExp(turn, 1)
+
+	result := new_big_float(wp).Mul(x, new_big_float(wp).SetFloat64(ctx.Angle.turn()))
+	return new_big_float(ctx.Precision).Quo(result, turn)
+}
@@ -0,0 +1,565 @@
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+var ErrDivisionByZero = errors.New("division by zero")
+
+// numeric_rank orders the numeric object types by how much they can
+// represent. Binary operations promote both operands to the higher rank.
+type numeric_rank int
+
+const (
+	rank_int numeric_rank = iota
+	rank_big_int
+	rank_big_rat
+	rank_decimal
+	rank_float64
+	rank_big_float
+	rank_complex
+)
+
+func rank_of(obj Object) (numeric_rank, error) {
+	switch obj.(type) {
+	case IntObject:
+		return rank_int, nil
+	case BigIntObject:
+		return rank_big_int, nil
+	case BigRatObject:
+		return rank_big_rat, nil
+	case DecimalObject:
+		return rank_decimal, nil
+	case Float64Object:
+		return rank_float64, nil
+	case BigFloatObject:
+		return rank_big_float, nil
+	case ComplexObject:
+		return rank_complex, nil
+	default:
+		return 0, fmt.Errorf("unsupported value %s", obj)
+	}
+}
+
+func to_float64(obj Object) (Float64Object, error) {
+	switch value := obj.(type) {
+	case Float64Object:
+		return value, nil
+	case IntObject:
+		f, _ := new(big.Float).SetInt(value.Big()).Float64()
+		return Float64Object{f}, nil
+	case BigIntObject:
+		f, _ := new(big.Float).SetInt(value.Value).Float64()
+		return Float64Object{f}, nil
+	case BigRatObject:
+		f, _ := value.Value.Float64()
+		return Float64Object{f}, nil
+	case DecimalObject:
+		f, _ := value.Rat().Float64()
+		return Float64Object{f}, nil
+	case BigFloatObject:
+		f, _ := value.Value.Float64()
+		return Float64Object{f}, nil
+	default:
+		return zero, fmt.Errorf("cannot use %s as a number", obj)
+	}
+}
+
+func to_big_rat(obj Object) *big.Rat {
+	switch value := obj.(type) {
+	case IntObject:
+		return new(big.Rat).SetInt(value.Big())
+	case BigIntObject:
+		return new(big.Rat).SetInt(value.Value)
+	case BigRatObject:
+		return value.Value
+	case DecimalObject:
+		return value.Rat()
+	}
+
+	return nil
+}
+
+// to_big_float converts any numeric object to a big.Float. Callers make sure
+// float64 values are finite, big.Float has no NaN.
+func to_big_float(obj Object, prec uint) *big.Float {
+	switch value := obj.(type) {
+	case Float64Object:
+		return new_big_float(min(prec, 53)).SetFloat64(value.Value)
+	case IntObject:
+		return new_big_float(prec).SetInt(value.Big())
+	case BigIntObject:
+		return new_big_float(prec).SetInt(value.Value)
+	case BigRatObject:
+		return new_big_float(prec).SetRat(value.Value)
+	case DecimalObject:
+		return new_big_float(prec).SetRat(value.Rat())
+	case BigFloatObject:
+		return value.Value
+	}
+
+	return nil
+}
+
+// normalize_rat collapses rationals with a denominator of one back into
+// integers so exact results print and compute as integers.
+func normalize_rat(r *big.Rat) Object {
+	if r.IsInt() {
+		return BigIntObject{new(big.Int).Set(r.Num())}
+	}
+
+	return BigRatObject{r}
+}
+
+// max_exact_pow_bits limits the size of exact integer and rational powers,
+// larger results are computed with big.Float instead.
+const max_exact_pow_bits = 1 << 20
+
+// exact_pow_fits reports whether the power of a base of the given bit length
+// to n stays within max_exact_pow_bits, without overflowing the product of
+// the two.
+func exact_pow_fits(bits int, n int64) bool {
+	if n == math.MinInt64 {
+		return false
+	}
+
+	return bits == 0 || abs_int64(n) <= max_exact_pow_bits/int64(bits)
+}
+
+// binary_op applies op to two numeric objects and brings the result into the
+// representation of the numeric mode. Operations on lists are applied element
+// by element and operations on quantities work out the unit of the result.
+func binary_op(ctx *CallContext, op Op, left, right Object) (Object, error) {
+	switch op {
+	case OpAddPercent, OpSubPercent, OpPercentOf:
+		return percent_op(ctx, op, left, right)
+	}
+
+	if any_list([]Object{left, right}) {
+		return list_op(ctx, op, left, right)
+	}
+
+	if any_quantity([]Object{left, right}) {
+		return quantity_op(ctx, op, left, right)
+	}
+
+	result, err := numeric_op(ctx, op, left, right)
+	if err != nil {
+		return nil, err
+	}
+
+	// powers like (-8)^(1/3) have no real result but a complex one
+	if op == OpPow && real_nan_result(result, []Object{left, right}) {
+		result, err = complex_operands_op(op, left, right)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ctx.normalize(result)
+}
+
+// percent_op works out a share of a base, the base is left for OpAddPercent
+// and OpSubPercent and right for OpPercentOf. The other operand is the number
+// in front of the %, it is divided by a hundred last so integers stay exact.
+func percent_op(ctx *CallContext, op Op, left, right Object) (Object, error) {
+	base, percent := left, right
+	if op == OpPercentOf {
+		base, percent = right, left
+	}
+
+	share, err := binary_op(ctx, OpMul, base, percent)
+	if err != nil {
+		return nil, err
+	}
+	share, err = binary_op(ctx, OpDiv, share, big_int(100))
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case OpAddPercent:
+		return binary_op(ctx, OpAdd, base, share)
+	case OpSubPercent:
+		return binary_op(ctx, OpSub, base, share)
+	}
+
+	return share, nil
+}
+
+func numeric_op(ctx *CallContext, op Op, left, right Object) (Object, error) {
+	switch op {
+	case OpAnd, OpOr, OpXor, OpShl, OpShr:
+		return bitwise_op(ctx, op, left, right)
+	case OpIntDiv:
+		return floor_div_op(ctx, left, right)
+	}
+
+	l, l_float := left.(Float64Object)
+	r, r_float := right.(Float64Object)
+	if l_float && r_float {
+		return float64_op(op, l.Value, r.Value), nil
+	}
+
+	left_rank, err := rank_of(left)
+	if err != nil {
+		return nil, err
+	}
+	right_rank, err := rank_of(right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch rank := max(left_rank, right_rank); {
+	case rank == rank_complex:
+		return complex_operands_op(op, left, right)
+	case left_rank == rank_int && right_rank == rank_int:
+		return int_op(ctx, op, left.(IntObject), right.(IntObject))
+	case rank <= rank_big_int:
+		return big_int_op(ctx, op, to_big_rat(left).Num(), to_big_rat(right).Num())
+	case rank == rank_big_rat:
+		return big_rat_op(ctx, op, to_big_rat(left), to_big_rat(right))
+	case rank == rank_decimal:
+		return decimal_op(ctx, op, to_decimal(ctx, left), to_decimal(ctx, right))
+	case rank == rank_float64, (l_float && !is_finite(l.Value)), (r_float && !is_finite(r.Value)):
+		// big.Float has no NaN, so non-finite floats keep the operation in float64
+		l, _ := to_float64(left)
+		r, _ := to_float64(right)
+		return float64_op(op, l.Value, r.Value), nil
+	default:
+		return big_float_op(ctx, op, to_big_float(left, ctx.Precision), to_big_float(right, ctx.Precision))
+	}
+}
+
+func complex_operands_op(op Op, left, right Object) (Object, error) {
+	l, err := to_complex(left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := to_complex(right)
+	if err != nil {
+		return nil, err
+	}
+
+	return complex_op(op, l, r)
+}
+
+func float64_op(op Op, left, right float64) Float64Object {
+	switch op {
+	case OpAdd:
+		return Float64Object{left + right}
+	case OpSub:
+		return Float64Object{left - right}
+	case OpMul:
+		return Float64Object{left * right}
+	case OpDiv:
+		return Float64Object{left / right}
+	case OpMod:
+		return Float64Object{math.Mod(left, right)}
+	case OpPow:
+		return Float64Object{math.Pow(left, right)}
+	}
+
+	return Float64Object{math.NaN()}
+}
+
+func big_int_op(ctx *CallContext, op Op, left, right *big.Int) (Object, error) {
+	switch op {
+	case OpAdd:
+		return BigIntObject{new(big.Int).Add(left, right)}, nil
+	case OpSub:
+		return BigIntObject{new(big.Int).Sub(left, right)}, nil
+	case OpMul:
+		return BigIntObject{new(big.Int).Mul(left, right)}, nil
+	case OpDiv:
+		if right.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return normalize_rat(new(big.Rat).SetFrac(left, right)), nil
+	case OpMod:
+		if right.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		// Rem truncates like math.Mod, the sign follows the dividend
+		return BigIntObject{new(big.Int).Rem(left, right)}, nil
+	case OpPow:
+		if right.Sign() < 0 {
+			return big_rat_op(ctx, op, new(big.Rat).SetInt(left), new(big.Rat).SetInt(right))
+		}
+		if right.IsInt64() && exact_pow_fits(left.BitLen(), right.Int64()) {
+			return BigIntObject{new(big.Int).Exp(left, right, nil)}, nil
+		}
+		return inexact_op(ctx, op, BigIntObject{left}, BigIntObject{right})
+	}
+
+	return nil, fmt.Errorf("unsupported operation %s", op)
+}
+
+func big_rat_op(ctx *CallContext, op Op, left, right *big.Rat) (Object, error) {
+	switch op {
+	case OpAdd:
+		return normalize_rat(new(big.Rat).Add(left, right)), nil
+	case OpSub:
+		return normalize_rat(new(big.Rat).Sub(left, right)), nil
+	case OpMul:
+		return normalize_rat(new(big.Rat).Mul(left, right)), nil
+	case OpDiv:
+		if right.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return normalize_rat(new(big.Rat).Quo(left, right)), nil
+	case OpMod:
+		if right.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		quotient := new(big.Rat).SetInt(rat_trunc(new(big.Rat).Quo(left, right)))
+		return normalize_rat(new(big.Rat).Sub(left, quotient.Mul(quotient, right))), nil
+	case OpPow:
+		if right.IsInt() && right.Num().IsInt64() {
+			n := right.Num().Int64()
+
+			if exact_pow_fits(max(left.Num().BitLen(), left.Denom().BitLen()), n) {
+				if n < 0 && left.Sign() == 0 {
+					return nil, ErrDivisionByZero
+				}
+
+				exponent := big.NewInt(abs_int64(n))
+				num := new(big.Int).Exp(left.Num(), exponent, nil)
+				denom := new(big.Int).Exp(left.Denom(), exponent, nil)
+				if n < 0 {
+					num, denom = denom, num
+				}
+
+				return normalize_rat(new(big.Rat).SetFrac(num, denom)), nil
+			}
+		}
+
+		return inexact_op(ctx, op, BigRatObject{left}, BigRatObject{right})
+	}
+
+	return nil, fmt.Errorf("unsupported operation %s", op)
+}
+
+// inexact_op computes results that cannot stay exact. BigMode keeps them in
+// big.Float, the other modes fall back to float64.
+func inexact_op(ctx *CallContext, op Op, left, right Object) (Object, error) {
+	if ctx.NumericMode != BigMode {
+		l, _ := to_float64(left)
+		r, _ := to_float64(right)
+		return float64_op(op, l.Value, r.Value), nil
+	}
+
+	return big_float_op(ctx, op, to_big_float(left, ctx.Precision), to_big_float(right, ctx.Precision))
+}
+
+func big_float_op(ctx *CallContext, op Op, left, right *big.Float) (result Object, err error) {
+	// results are only as precise as their least precise operand, values that
+	// went through float64 builtins carry no more than 53 bits
+	prec := min(ctx.Precision, left.Prec(), right.Prec())
+
+	// operations like Inf - Inf panic with big.ErrNaN instead of returning NaN
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(big.ErrNaN); !ok {
+				panic(r)
+			}
+			result, err = Float64Object{math.NaN()}, nil
+		}
+	}()
+
+	switch op {
+	case OpAdd:
+		return BigFloatObject{new_big_float(prec).Add(left, right)}, nil
+	case OpSub:
+		return BigFloatObject{new_big_float(prec).Sub(left, right)}, nil
+	case OpMul:
+		return BigFloatObject{new_big_float(prec).Mul(left, right)}, nil
+	case OpDiv:
+		if right.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return BigFloatObject{new_big_float(prec).Quo(left, right)}, nil
+	case OpMod:
+		if right.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		quotient := new_big_float(prec+guard_bits).Quo(left, right)
+		if quotient.IsInf() {
+			return Float64Object{math.NaN()}, nil
+		}
+		truncated, _ := quotient.Int(nil)
+		quotient.SetInt(truncated).Mul(quotient, right)
+		return BigFloatObject{new_big_float(prec).Sub(left, quotient)}, nil
+	case OpPow:
+		result := big_pow(left, right, prec)
+		if result == nil {
+			return Float64Object{math.NaN()}, nil
+		}
+		return BigFloatObject{result}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported operation %s", op)
+}
+
+// compare_objects orders two numeric objects the way cmp.Compare does,
+// promoting them to a common representation first.
+func compare_objects(ctx *CallContext, left, right Object) (int, error) {
+	left_rank, err := rank_of(left)
+	if err != nil {
+		return 0, err
+	}
+	right_rank, err := rank_of(right)
+	if err != nil {
+		return 0, err
+	}
+
+	l, l_float := left.(Float64Object)
+	r, r_float := right.(Float64Object)
+
+	switch rank := max(left_rank, right_rank); {
+	case rank == rank_complex:
+		return 0, errors.New("complex numbers cannot be compared")
+	case rank <= rank_decimal:
+		return to_big_rat(left).Cmp(to_big_rat(right)), nil
+	case rank == rank_float64, (l_float && !is_finite(l.Value)), (r_float && !is_finite(r.Value)):
+		l, _ := to_float64(left)
+		r, _ := to_float64(right)
+		switch {
+		case l.Value < r.Value:
+			return -1, nil
+		case l.Value > r.Value:
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return to_big_float(left, ctx.Precision).Cmp(to_big_float(right, ctx.Precision)), nil
+	}
+}
+
+// exact_int returns the integer value of an object if it holds one exactly.
+func exact_int(obj Object) (*big.Int, bool) {
+	switch value := obj.(type) {
+	case IntObject:
+		return value.Big(), true
+	case BigIntObject:
+		return value.Value, true
+	case BigRatObject:
+		if value.Value.IsInt() {
+			return value.Value.Num(), true
+		}
+	case DecimalObject:
+		if r := value.Rat(); r.IsInt() {
+			return r.Num(), true
+		}
+	case BigFloatObject:
+		if value.Value.IsInt() && !value.Value.IsInf() {
+			result, _ := value.Value.Int(nil)
+			return result, true
+		}
+	case Float64Object:
+		if value.Value == math.Trunc(value.Value) && !math.IsInf(value.Value, 0) {
+			result, _ := new(big.Float).SetFloat64(value.Value).Int(nil)
+			return result, true
+		}
+	}
+
+	return nil, false
+}
+
+// exact_rat returns the exact rational value of any finite numeric object.
+func exact_rat(obj Object) (*big.Rat, bool) {
+	switch value := obj.(type) {
+	case IntObject, BigIntObject, BigRatObject, DecimalObject:
+		return to_big_rat(value), true
+	case BigFloatObject:
+		if value.Value.IsInf() {
+			return nil, false
+		}
+		result, _ := value.Value.Rat(nil)
+		return result, true
+	case Float64Object:
+		if !is_finite(value.Value) {
+			return nil, false
+		}
+		return new(big.Rat).SetFloat64(value.Value), true
+	}
+
+	return nil, false
+}
+
+// as_big_float converts numeric objects for the big.Float implementations of
+// the builtins. Outside of BigMode and for non-finite float64 values it fails,
+// which leaves the call to the float64 fallback.
+func as_big_float(ctx *CallContext, obj Object) (*big.Float, bool) {
+	if ctx.NumericMode != BigMode {
+		return nil, false
+	}
+
+	switch value := obj.(type) {
+	case Float64Object:
+		if !is_finite(value.Value) {
+			return nil, false
+		}
+	case BigIntObject, BigRatObject, DecimalObject, BigFloatObject:
+	default:
+		return nil, false
+	}
+
+	return to_big_float(obj, ctx.Precision), true
+}
+
+func object_abs(obj Object) Object {
+	switch value := obj.(type) {
+	case IntObject:
+		// the absolute value of the smallest signed integer overflows the
+		// word, normalize takes care of it
+		return BigIntObject{new(big.Int).Abs(value.Big())}
+	case BigIntObject:
+		return BigIntObject{new(big.Int).Abs(value.Value)}
+	case BigRatObject:
+		return BigRatObject{new(big.Rat).Abs(value.Value)}
+	case DecimalObject:
+		return DecimalObject{new(big.Int).Abs(value.Unscaled), value.Scale}
+	case BigFloatObject:
+		return BigFloatObject{new(big.Float).Abs(value.Value)}
+	}
+
+	return nil
+}
+
+func object_neg(obj Object) Object {
+	switch value := obj.(type) {
+	case IntObject:
+		return BigIntObject{new(big.Int).Neg(value.Big())}
+	case BigIntObject:
+		return BigIntObject{new(big.Int).Neg(value.Value)}
+	case BigRatObject:
+		return BigRatObject{new(big.Rat).Neg(value.Value)}
+	case DecimalObject:
+		return DecimalObject{new(big.Int).Neg(value.Unscaled), value.Scale}
+	case BigFloatObject:
+		return BigFloatObject{new(big.Float).Neg(value.Value)}
+	}
+
+	return nil
+}
+
+// exact_sqrt returns the square root of integers and rationals whose
+// numerator and denominator are both perfect squares.
+func exact_sqrt(obj Object) (Object, bool) {
+	r := to_big_rat(obj)
+	if r == nil || r.Sign() < 0 {
+		return nil, false
+	}
+
+	num := new(big.Int).Sqrt(r.Num())
+	denom := new(big.Int).Sqrt(r.Denom())
+	if new(big.Int).Mul(num, num).Cmp(r.Num()) != 0 || new(big.Int).Mul(denom, denom).Cmp(r.Denom()) != 0 {
+		return nil, false
+	}
+
+	return normalize_rat(new(big.Rat).SetFrac(num, denom)), true
+}
@@ -0,0 +1,24 @@
+package calc
+
+import "testing"
+
+// TestHugePowers checks that exponents whose exact result would not fit are
+// computed inexactly rather than overflowing the size check and hanging.
+func TestHugePowers(t *testing.T) {
+	big_mode := []Option{WithNumericMode(BigMode)}
+	rational := []Option{WithNumericMode(RationalMode)}
+
+	check_eval_cases(t, []eval_case{
+		{Src: "3^4611686018427387904", Opts: big_mode, Expected: "+Inf"},
+		{Src: "2^4611686018427387904", Opts: big_mode, Expected: "+Inf"},
+		{Src: "2^-9223372036854775808", Opts: big_mode, Expected: "0"},
+		{Src: "0^9223372036854775807", Opts: big_mode, Expected: "0"},
+		{Src: "2^100", Opts: big_mode, Expected: "1267650600228229401496703205376"},
+		{Src: "3^4611686018427387904", Opts: rational, Expected: "+Inf"},
+		{Src: "2^4611686018427387904", Opts: rational, Expected: "+Inf"},
+		{Src: "0.5^9223372036854775807", Opts: rational, Expected: "0"},
+		{Src: "2^-9223372036854775808", Opts: rational, Expected: "0"},
+		{Src: "(1/2)^-3", Opts: rational, Expected: "8"},
+		{Src: "(2/3)^2 - 4/9", Opts: rational, Expected: "0"},
+	})
+}
@@ -1,4 +1,4 @@
-package main
+package calc
 
 import (
 	"fmt"
@@ -19,27 +19,82 @@ const (
 	OpTypeDiv
 	OpTypeMod
 	OpTypePow
+	OpTypeIntDiv
+	OpTypeAnd
+	OpTypeOr
+	OpTypeXor
+	OpTypeShl
+	OpTypeShr
+	OpTypeNot
+	OpTypeAddPercent
+	OpTypeSubPercent
+	OpTypeOf
+	OpTypeOff
 )
 
 var op_type_map = map[OpType]string{
-	OpTypeAdd: "+",
-	OpTypeSub: "-",
-	OpTypeMul: "*",
-	OpTypeDiv: "/",
-	OpTypeMod: "%",
-	OpTypePow: "^",
+	OpTypeAdd:    "+",
+	OpTypeSub:    "-",
+	OpTypeMul:    "*",
+	OpTypeDiv:    "/",
+	OpTypeMod:    "%",
+	OpTypePow:    "^",
+	OpTypeIntDiv: "//",
+	OpTypeAnd:    "&",
+	OpTypeOr:     "|",
+	OpTypeXor:    "xor",
+	OpTypeShl:    "<<",
+	OpTypeShr:    ">>",
+	OpTypeNot:    "~",
+	// adding and subtracting a percentage are written like the plain ones
+	OpTypeAddPercent: "+",
+	OpTypeSubPercent: "-",
+	OpTypeOf:         "of",
+	OpTypeOff:        "off",
 }
 
+// FloatLiteralExpr keeps the source text of the number next to its float
+// value so the numeric modes can parse it exactly.
 type FloatLiteralExpr struct {
-	Value float64
+	Value   float64
+	Literal string
 }
 
 func (expr FloatLiteralExpr) String() string {
 	return fmt.Sprintf("%f", expr.Value)
 }
 
-func f_literal(value float64) FloatLiteralExpr {
-	return FloatLiteralExpr{Value: value}
+func f_literal(value float64, literal string) FloatLiteralExpr {
+	return FloatLiteralExpr{Value: value, Literal: literal}
+}
+
+// ImaginaryLiteralExpr is a number literal with an i suffix like 4i, its
+// value is the imaginary part.
+type ImaginaryLiteralExpr struct {
+	Value   float64
+	Literal string
+}
+
+func (expr ImaginaryLiteralExpr) String() string {
+	return fmt.Sprintf("%fi", expr.Value)
+}
+
+func i_literal(value float64, literal string) ImaginaryLiteralExpr {
+	return ImaginaryLiteralExpr{Value: value, Literal: literal}
+}
+
+// QuantityExpr is a number literal with a unit like 5 km or 9.8 m/s^2.
+type QuantityExpr struct {
+	Value FloatLiteralExpr
+	Unit  Unit
+}
+
+func (expr QuantityExpr) String() string {
+	return fmt.Sprintf("%s %s", expr.Value.String(), expr.Unit)
+}
+
+func quantity_expr(value FloatLiteralExpr, unit Unit) QuantityExpr {
+	return QuantityExpr{Value: value, Unit: unit}
 }
 
 type ConstLiteralExpr struct {
@@ -54,27 +109,51 @@ func c_literal(name string) ConstLiteralExpr {
 	return ConstLiteralExpr{Name: name}
 }
 
+// BinaryExpr keeps the location of its operator, errors of the operation are
+// reported there.
 type BinaryExpr struct {
-	Left  Expr
-	Right Expr
-	Op    OpType
+	Left     Expr
+	Right    Expr
+	Op       OpType
+	Location Location
 }
 
 func (expr BinaryExpr) String() string {
 	return fmt.Sprintf("%s %s %s", expr.Left.String(), op_type_map[expr.Op], expr.Right.String())
 }
 
-func binary_expr(left, right Expr, op OpType) BinaryExpr {
+func binary_expr(left, right Expr, op OpType, location Location) BinaryExpr {
 	return BinaryExpr{
-		Left:  left,
-		Right: right,
-		Op:    op,
+		Left:     left,
+		Right:    right,
+		Op:       op,
+		Location: location,
 	}
 }
 
+type UnaryExpr struct {
+	Expr Expr
+	Op   OpType
+}
+
+func (expr UnaryExpr) String() string {
+	return fmt.Sprintf("%s%s", op_type_map[expr.Op], expr.Expr.String())
+}
+
+func unary_expr(expr Expr, op OpType) UnaryExpr {
+	return UnaryExpr{
+		Expr: expr,
+		Op:   op,
+	}
+}
+
+// FnCallExpr keeps the location of the function name, errors of the call are
+// reported there. Calls the parser desugars, like n! to fact(n), point at
+// their operator.
 type FnCallExpr struct {
-	Name string
-	Args []Expr
+	Name     string
+	Args     []Expr
+	Location Location
 }
 
 func (expr FnCallExpr) String() string {
@@ -94,6 +173,98 @@ func fn_call(name string, args ...Expr) FnCallExpr {
 	}
 }
 
+// ConvertExpr expresses the value of Expr in Unit, written with the to or in
+// operator.
+type ConvertExpr struct {
+	Expr     Expr
+	Unit     Unit
+	Location Location
+}
+
+func (expr ConvertExpr) String() string {
+	return fmt.Sprintf("%s to %s", expr.Expr.String(), expr.Unit)
+}
+
+func convert_expr(expr Expr, unit Unit, location Location) ConvertExpr {
+	return ConvertExpr{
+		Expr:     expr,
+		Unit:     unit,
+		Location: location,
+	}
+}
+
+// PercentExpr is an operand followed by a % that is not the modulo operator,
+// like the 10% of 200 + 10%.
+type PercentExpr struct {
+	Expr     Expr
+	Location Location
+}
+
+func (expr PercentExpr) String() string {
+	return fmt.Sprintf("%s%%", expr.Expr.String())
+}
+
+func percent_expr(expr Expr, location Location) PercentExpr {
+	return PercentExpr{
+		Expr:     expr,
+		Location: location,
+	}
+}
+
+// AngleModeExpr is a program starting with a mode statement like mode deg,
+// Expr is evaluated with the angle mode.
+type AngleModeExpr struct {
+	Mode AngleMode
+	Expr Expr
+}
+
+func (expr AngleModeExpr) String() string {
+	return fmt.Sprintf("mode %s; %s", expr.Mode, expr.Expr.String())
+}
+
+func angle_mode_expr(mode AngleMode, expr Expr) AngleModeExpr {
+	return AngleModeExpr{
+		Mode: mode,
+		Expr: expr,
+	}
+}
+
+type ListExpr struct {
+	Items []Expr
+}
+
+func (expr ListExpr) String() string {
+	items := []string{}
+
+	for _, item := range expr.Items {
+		items = append(items, item.String())
+	}
+
+	return fmt.Sprintf("[%s]", strings.Join(items, ", "))
+}
+
+func list_expr(items ...Expr) ListExpr {
+	return ListExpr{
+		Items: items,
+	}
+}
+
+type IndexExpr struct {
+	Expr  Expr
+	Index Expr
+}
+
+func (expr IndexExpr) String() string {
+	return fmt.Sprintf("%s[%s]", expr.Expr.String(), expr.Index.String())
+}
+
+func index_expr(expr, index Expr) IndexExpr {
+	return IndexExpr{
+		Expr:  expr,
+		Index: index,
+	}
+}
+
 type GroupExpr struct {
 	Expr Expr
 }
@@ -108,8 +279,16 @@ func group_expr(expr Expr) GroupExpr {
 	}
 }
 
-func (e FloatLiteralExpr) expr() {}
-func (e ConstLiteralExpr) expr() {}
-func (e BinaryExpr) expr()       {}
-func (e FnCallExpr) expr()       {}
-func (e GroupExpr) expr()        {}
+func (e FloatLiteralExpr) expr()     {}
+func (e ImaginaryLiteralExpr) expr() {}
+func (e QuantityExpr) expr()         {}
+func (e ConstLiteralExpr) expr()     {}
+func (e BinaryExpr) expr()           {}
+func (e UnaryExpr) expr()            {}
+func (e FnCallExpr) expr()           {}
+func (e ConvertExpr) expr()          {}
+func (e PercentExpr) expr()          {}
+func (e AngleModeExpr) expr()        {}
+func (e ListExpr) expr()             {}
+func (e IndexExpr) expr()            {}
+func (e GroupExpr) expr()            {}
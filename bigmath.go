@@ -0,0 +1,653 @@
+package calc
+
+import (
+	"math"
+	"math/big"
+)
+
+// guard_bits are added to the working precision of the series below so the
+// result is still correct to the requested precision after rounding.
+const guard_bits = 64
+
+func new_big_float(prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec)
+}
+
+// big_atanh_series sums z + z³/3 + z⁵/5 + ... which converges quickly for the
+// small |z| the callers below use.
+func big_atanh_series(z *big.Float, prec uint) *big.Float {
+	result := new_big_float(prec).Set(z)
+	power := new_big_float(prec).Set(z)
+	z2 := new_big_float(prec).Mul(z, z)
+	term := new_big_float(prec)
+	limit := new_big_float(prec).SetMantExp(big.NewFloat(1), -int(prec))
+
+	for n := int64(3); ; n += 2 {
+		power.Mul(power, z2)
+		term.Quo(power, new_big_float(prec).SetInt64(n))
+		result.Add(result, term)
+
+		if term.Sign() == 0 || new_big_float(prec).Abs(term).Cmp(limit) < 0 {
+			return result
+		}
+	}
+}
+
+// big_atan_series sums the alternating Taylor series x - x³/3 + x⁵/5 - ...
+// which converges quickly for the small |x| the callers below use.
+func big_atan_series(x *big.Float, prec uint) *big.Float {
+	x2 := new_big_float(prec).Mul(x, x)
+	result := new_big_float(prec).Set(x)
+	power := new_big_float(prec).Set(x)
+	term := new_big_float(prec)
+	limit := new_big_float(prec).SetMantExp(big.NewFloat(1), -int(prec))
+
+	for k := int64(3); ; k += 2 {
+		power.Mul(power, x2)
+		term.Quo(power, new_big_float(prec).SetInt64(k))
+
+		if (k/2)%2 == 1 {
+			result.Sub(result, term)
+		} else {
+			result.Add(result, term)
+		}
+
+		if term.Sign() == 0 || new_big_float(prec).Abs(term).Cmp(limit) < 0 {
+			return result
+		}
+	}
+}
+
+// big_atan_inv computes atan(1/n) with its alternating Taylor series.
+func big_atan_inv(n int64, prec uint) *big.Float {
+	x := new_big_float(prec).Quo(big.NewFloat(1), new_big_float(prec).SetInt64(n))
+	return big_atan_series(x, prec)
+}
+
+// big_pi uses Machin's formula, pi = 16 atan(1/5) - 4 atan(1/239).
+func big_pi(prec uint) *big.Float {
+	wp := prec + guard_bits
+	a := big_atan_inv(5, wp)
+	b := big_atan_inv(239, wp)
+
+	a.Mul(a, new_big_float(wp).SetInt64(16))
+	b.Mul(b, new_big_float(wp).SetInt64(4))
+
+	return new_big_float(prec).Sub(a, b)
+}
+
+// big_ln2 uses ln(2) = 2 atanh(1/3).
+func big_ln2(prec uint) *big.Float {
+	wp := prec + guard_bits
+	z := new_big_float(wp).Quo(big.NewFloat(1), new_big_float(wp).SetInt64(3))
+	result := big_atanh_series(z, wp)
+
+	return new_big_float(prec).Mul(result, big.NewFloat(2))
+}
+
+// big_log splits x into m * 2^e with m in [0.5, 1), so that
+// log(x) = 2 atanh((m-1)/(m+1)) + e ln(2) where the series converges fast.
+// x in [1, 2) is used as is, e ln(2) would cancel the series near 1.
+func big_log(x *big.Float, prec uint) *big.Float {
+	wp := prec + guard_bits
+	m := new_big_float(wp)
+	e := x.MantExp(m)
+	if e == 1 {
+		m.SetMantExp(m, 1)
+		e = 0
+	}
+
+	numerator := new_big_float(wp).Sub(m, big.NewFloat(1))
+	denominator := new_big_float(wp).Add(m, big.NewFloat(1))
+	z := new_big_float(wp).Quo(numerator, denominator)
+
+	result := big_atanh_series(z, wp)
+	result.Mul(result, big.NewFloat(2))
+
+	if e != 0 {
+		ln2 := big_ln2(wp)
+		ln2.Mul(ln2, new_big_float(wp).SetInt64(int64(e)))
+		result.Add(result, ln2)
+	}
+
+	return new_big_float(prec).Set(result)
+}
+
+// big_exp halves x until it is small, sums the Taylor series and squares the
+// result back up again.
+func big_exp(x *big.Float, prec uint) *big.Float {
+	if x.Sign() == 0 {
+		return new_big_float(prec).SetInt64(1)
+	}
+
+	// anything past this overflows or underflows the exponent of a big.Float
+	if x.MantExp(nil) > 32 {
+		if x.Sign() > 0 {
+			return new_big_float(prec).SetInf(false)
+		}
+		return new_big_float(prec)
+	}
+
+	halvings := max(x.MantExp(nil)+8, 0)
+	wp := prec + guard_bits + uint(halvings)
+
+	r := new_big_float(wp).SetMantExp(x, -halvings)
+	result := new_big_float(wp).SetInt64(1)
+	term := new_big_float(wp).SetInt64(1)
+	limit := new_big_float(wp).SetMantExp(big.NewFloat(1), -int(wp))
+
+	for n := int64(1); ; n++ {
+		term.Mul(term, r)
+		term.Quo(term, new_big_float(wp).SetInt64(n))
+		result.Add(result, term)
+
+		if term.Sign() == 0 || new_big_float(wp).Abs(term).Cmp(limit) < 0 {
+			break
+		}
+	}
+
+	for i := 0; i < halvings; i++ {
+		result.Mul(result, result)
+	}
+
+	return new_big_float(prec).Set(result)
+}
+
+// big_pow raises x to y, using exact repeated squaring for integer exponents
+// and exp(y log x) otherwise. It returns nil when the result is not real.
+func big_pow(x, y *big.Float, prec uint) *big.Float {
+	if y.IsInt() && y.MantExp(nil) <= 32 {
+		n, _ := y.Int64()
+		wp := prec + guard_bits
+		result := new_big_float(wp).SetInt64(1)
+		base := new_big_float(wp).Set(x)
+
+		for k := abs_int64(n); k > 0; k >>= 1 {
+			if k&1 == 1 {
+				result.Mul(result, base)
+			}
+			base.Mul(base, base)
+		}
+
+		if n < 0 {
+			if result.Sign() == 0 {
+				return new_big_float(prec).SetInf(false)
+			}
+			result.Quo(new_big_float(wp).SetInt64(1), result)
+		}
+
+		return new_big_float(prec).Set(result)
+	}
+
+	switch x.Sign() {
+	case -1:
+		return nil
+	case 0:
+		if y.Sign() < 0 {
+			return new_big_float(prec).SetInf(false)
+		}
+		return new_big_float(prec)
+	}
+
+	wp := prec + guard_bits
+	exponent := big_log(x, wp)
+	exponent.Mul(exponent, y)
+
+	return big_exp(exponent, prec)
+}
+
+// max_reduced_exp bounds the binary exponent of the arguments the
+// trigonometric functions below reduce, larger ones fall back to float64.
+const max_reduced_exp = 4096
+
+// big_neg negates x in place, but keeps zero positive so sin(pi) does not
+// print as -0.
+func big_neg(x *big.Float) *big.Float {
+	if x.Sign() != 0 {
+		x.Neg(x)
+	}
+
+	return x
+}
+
+// big_sin_cos_series sums the Taylor series of sin and cos of an angle that
+// has been reduced to at most π/2 in magnitude.
+func big_sin_cos_series(r *big.Float, prec uint) (*big.Float, *big.Float) {
+	sin := new_big_float(prec)
+	cos := new_big_float(prec).SetInt64(1)
+	term := new_big_float(prec).SetInt64(1)
+	limit := new_big_float(prec).SetMantExp(big.NewFloat(1), -int(prec))
+
+	for k := int64(1); ; k++ {
+		term.Mul(term, r)
+		term.Quo(term, new_big_float(prec).SetInt64(k))
+
+		sum := cos
+		if k%2 == 1 {
+			sum = sin
+		}
+		if k%4 == 2 || k%4 == 3 {
+			sum.Sub(sum, term)
+		} else {
+			sum.Add(sum, term)
+		}
+
+		if term.Sign() == 0 || new_big_float(prec).Abs(term).Cmp(limit) < 0 {
+			return sin, cos
+		}
+	}
+}
+
+// big_quadrant turns the sine and cosine of r into those of r + kπ/2.
+func big_quadrant(k *big.Int, sin, cos *big.Float) (*big.Float, *big.Float) {
+	switch new(big.Int).And(k, big.NewInt(3)).Int64() {
+	case 1:
+		return cos, big_neg(sin)
+	case 2:
+		return big_neg(sin), big_neg(cos)
+	case 3:
+		return big_neg(cos), sin
+	}
+
+	return sin, cos
+}
+
+// big_sin_cos reduces x by the multiple of π/2 closest to it and returns the
+// sine and cosine of x. It returns nils for infinite or huge x.
+func big_sin_cos(x *big.Float, prec uint) (*big.Float, *big.Float) {
+	if x.IsInf() || x.MantExp(nil) > max_reduced_exp {
+		return nil, nil
+	}
+
+	// the reduction cancels the integer bits of x / (π/2)
+	wp := prec + guard_bits + uint(max(x.MantExp(nil), 0))
+	half_pi := big_pi(wp)
+	half_pi.SetMantExp(half_pi, -1)
+
+	quotient := new_big_float(wp).Quo(x, half_pi)
+	if quotient.Sign() < 0 {
+		quotient.Sub(quotient, big.NewFloat(0.5))
+	} else {
+		quotient.Add(quotient, big.NewFloat(0.5))
+	}
+	k, _ := quotient.Int(nil)
+
+	r := new_big_float(wp).Mul(half_pi, new_big_float(wp).SetInt(k))
+	r.Sub(x, r)
+
+	sin, cos := big_sin_cos_series(r, wp)
+	sin, cos = big_quadrant(k, sin, cos)
+	return new_big_float(prec).Set(sin), new_big_float(prec).Set(cos)
+}
+
+// big_sin_cos_degrees reduces the angle d in degrees to a quadrant exactly,
+// so the sine and cosine of multiples of 90 come out exact like in sin_deg.
+func big_sin_cos_degrees(d *big.Rat, prec uint) (*big.Float, *big.Float) {
+	wp := prec + guard_bits
+	k := rat_floor(new(big.Rat).Quo(d, big.NewRat(90, 1)))
+
+	angle := new(big.Rat).Mul(new(big.Rat).SetInt(k), big.NewRat(90, 1))
+	angle.Sub(d, angle)
+
+	r := new_big_float(wp).SetRat(angle)
+	r.Mul(r, big_pi(wp))
+	r.Quo(r, new_big_float(wp).SetInt64(180))
+
+	sin, cos := big_sin_cos_series(r, wp)
+	sin, cos = big_quadrant(k, sin, cos)
+	return new_big_float(prec).Set(sin), new_big_float(prec).Set(cos)
+}
+
+// big_atan brings |x| to at most 1 with atan(x) = π/2 - atan(1/x) and halves
+// it a few times with atan(x) = 2 atan(x / (1 + √(1 + x²))) so the series
+// converges quickly.
+func big_atan(x *big.Float, prec uint) *big.Float {
+	const halvings = 4
+
+	wp := prec + guard_bits
+	z := new_big_float(wp).Abs(x)
+
+	inverted := z.Cmp(big.NewFloat(1)) > 0
+	if inverted {
+		z.Quo(big.NewFloat(1), z)
+	}
+
+	for i := 0; i < halvings; i++ {
+		root := new_big_float(wp).Mul(z, z)
+		root.Add(root, big.NewFloat(1))
+		root = new_big_float(wp).Sqrt(root)
+		root.Add(root, big.NewFloat(1))
+		z.Quo(z, root)
+	}
+
+	result := big_atan_series(z, wp)
+	result.SetMantExp(result, halvings)
+
+	if inverted {
+		half_pi := big_pi(wp)
+		half_pi.SetMantExp(half_pi, -1)
+		result.Sub(half_pi, result)
+	}
+	if x.Sign() < 0 {
+		result.Neg(result)
+	}
+
+	return new_big_float(prec).Set(result)
+}
+
+// big_asin uses asin(x) = atan(x / √(1 - x²)). It returns nil outside of
+// [-1, 1].
+func big_asin(x *big.Float, prec uint) *big.Float {
+	if new_big_float(prec).Abs(x).Cmp(big.NewFloat(1)) > 0 {
+		return nil
+	}
+
+	wp := prec + guard_bits
+	// (1 - x)(1 + x) rather than 1 - x² keeps the digits near |x| = 1
+	root := new_big_float(wp).Sub(big.NewFloat(1), x)
+	root.Mul(root, new_big_float(wp).Add(big.NewFloat(1), x))
+	root = new_big_float(wp).Sqrt(root)
+
+	return big_atan(new_big_float(wp).Quo(x, root), prec)
+}
+
+// big_acos uses acos(x) = 2 atan(√((1 - x) / (1 + x))), which unlike
+// π/2 - asin(x) does not cancel near x = 1. It returns nil outside of [-1, 1].
+func big_acos(x *big.Float, prec uint) *big.Float {
+	if new_big_float(prec).Abs(x).Cmp(big.NewFloat(1)) > 0 {
+		return nil
+	}
+
+	wp := prec + guard_bits
+	ratio := new_big_float(wp).Sub(big.NewFloat(1), x)
+	ratio.Quo(ratio, new_big_float(wp).Add(big.NewFloat(1), x))
+
+	result := big_atan(new_big_float(wp).Sqrt(ratio), wp)
+	result.SetMantExp(result, 1)
+
+	return new_big_float(prec).Set(result)
+}
+
+// big_atan2 picks the quadrant of atan(y / x) from the signs of y and x. It
+// returns nil when both are infinite.
+func big_atan2(y, x *big.Float, prec uint) *big.Float {
+	if y.IsInf() && x.IsInf() {
+		return nil
+	}
+
+	wp := prec + guard_bits
+	pi := big_pi(wp)
+
+	switch {
+	case x.Sign() > 0:
+		return big_atan(new_big_float(wp).Quo(y, x), prec)
+	case x.Sign() < 0:
+		result := big_atan(new_big_float(wp).Quo(y, x), wp)
+		if y.Sign() < 0 {
+			result.Sub(result, pi)
+		} else {
+			result.Add(result, pi)
+		}
+		return new_big_float(prec).Set(result)
+	case y.Sign() > 0:
+		return new_big_float(prec).Set(pi.SetMantExp(pi, -1))
+	case y.Sign() < 0:
+		return big_neg(new_big_float(prec).Set(pi.SetMantExp(pi, -1)))
+	}
+
+	return new_big_float(prec)
+}
+
+// small_arg_prec adds the bits lost to cancellation in e^x - e^-x and
+// log(1 + x) for x close to zero to the working precision.
+func small_arg_prec(x *big.Float, prec uint) uint {
+	if x.Sign() == 0 {
+		return prec + guard_bits
+	}
+
+	return prec + guard_bits + uint(max(-x.MantExp(nil), 0))
+}
+
+func big_sinh(x *big.Float, prec uint) *big.Float {
+	wp := small_arg_prec(x, prec)
+	e := big_exp(x, wp)
+	result := new_big_float(wp).Quo(big.NewFloat(1), e)
+	result.Sub(e, result)
+
+	return new_big_float(prec).Set(result.SetMantExp(result, -1))
+}
+
+func big_cosh(x *big.Float, prec uint) *big.Float {
+	wp := prec + guard_bits
+	e := big_exp(x, wp)
+	result := new_big_float(wp).Quo(big.NewFloat(1), e)
+	result.Add(e, result)
+
+	return new_big_float(prec).Set(result.SetMantExp(result, -1))
+}
+
+// big_tanh uses tanh(x) = (e^2x - 1) / (e^2x + 1), which is 1 once e^2x
+// overflows.
+func big_tanh(x *big.Float, prec uint) *big.Float {
+	wp := small_arg_prec(x, prec)
+	e := big_exp(new_big_float(wp).SetMantExp(x, 1), wp)
+	if e.IsInf() {
+		return new_big_float(prec).SetInt64(1)
+	}
+
+	numerator := new_big_float(wp).Sub(e, big.NewFloat(1))
+	denominator := new_big_float(wp).Add(e, big.NewFloat(1))
+
+	return new_big_float(prec).Quo(numerator, denominator)
+}
+
+// big_asinh uses asinh(x) = log(|x| + √(x² + 1)) with the sign of x.
+func big_asinh(x *big.Float, prec uint) *big.Float {
+	if x.Sign() == 0 {
+		return new_big_float(prec)
+	}
+
+	wp := small_arg_prec(x, prec)
+	z := new_big_float(wp).Abs(x)
+	root := new_big_float(wp).Mul(z, z)
+	root.Add(root, big.NewFloat(1))
+	z.Add(z, new_big_float(wp).Sqrt(root))
+
+	result := big_log(z, wp)
+	if x.Sign() < 0 {
+		result.Neg(result)
+	}
+
+	return new_big_float(prec).Set(result)
+}
+
+// big_acosh uses acosh(x) = log(x + √((x - 1)(x + 1))). It returns nil for x
+// below 1.
+func big_acosh(x *big.Float, prec uint) *big.Float {
+	if x.Cmp(big.NewFloat(1)) < 0 {
+		return nil
+	}
+
+	below := new_big_float(prec+guard_bits).Sub(x, big.NewFloat(1))
+	wp := small_arg_prec(below, prec)
+	root := new_big_float(wp).Sub(x, big.NewFloat(1))
+	root.Mul(root, new_big_float(wp).Add(x, big.NewFloat(1)))
+	root = new_big_float(wp).Sqrt(root)
+
+	return new_big_float(prec).Set(big_log(root.Add(root, x), wp))
+}
+
+// big_atanh uses atanh(x) = log((1 + x) / (1 - x)) / 2. It returns nil
+// unless |x| < 1.
+func big_atanh(x *big.Float, prec uint) *big.Float {
+	if new_big_float(prec).Abs(x).Cmp(big.NewFloat(1)) >= 0 {
+		return nil
+	}
+	if x.Sign() == 0 {
+		return new_big_float(prec)
+	}
+
+	wp := small_arg_prec(x, prec)
+	ratio := new_big_float(wp).Add(big.NewFloat(1), x)
+	ratio.Quo(ratio, new_big_float(wp).Sub(big.NewFloat(1), x))
+
+	result := big_log(ratio, wp)
+	return new_big_float(prec).Set(result.SetMantExp(result, -1))
+}
+
+// big_cbrt refines the float64 cube root of the mantissa with Newton steps,
+// each of which doubles the correct bits. The exponent is divided separately
+// so huge and tiny x do not over- or underflow the float64 guess.
+func big_cbrt(x *big.Float, prec uint) *big.Float {
+	if x.Sign() == 0 || x.IsInf() {
+		return new_big_float(prec).Set(x)
+	}
+
+	wp := prec + guard_bits
+	mantissa := new(big.Float)
+	exp := x.MantExp(mantissa)
+	rest := exp % 3
+	if rest < 0 {
+		rest += 3
+	}
+
+	m, _ := mantissa.Float64()
+	y := new_big_float(wp).SetFloat64(math.Cbrt(math.Ldexp(m, rest)))
+	y.SetMantExp(y, (exp-rest)/3)
+
+	for bits := uint(50); bits < 2*wp; bits *= 2 {
+		// y -= (y³ - x) / 3y²
+		square := new_big_float(wp).Mul(y, y)
+		step := new_big_float(wp).Mul(square, y)
+		step.Sub(step, x)
+		step.Quo(step, square.Mul(square, big.NewFloat(3)))
+		y.Sub(y, step)
+	}
+
+	return new_big_float(prec).Set(y)
+}
+
+// big_log_base divides the natural logarithm of x by that of base, powers of
+// two come out exact in base 2.
+func big_log_base(x *big.Float, base int64, prec uint) *big.Float {
+	if base == 2 {
+		mantissa := new(big.Float)
+		exp := x.MantExp(mantissa)
+		if mantissa.Cmp(big.NewFloat(0.5)) == 0 {
+			return new_big_float(prec).SetInt64(int64(exp - 1))
+		}
+	}
+
+	wp := prec + guard_bits
+	result := big_log(x, wp)
+	result.Quo(result, big_log(new_big_float(wp).SetInt64(base), wp))
+
+	return new_big_float(prec).Set(result)
+}
+
+func abs_int64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+// big_const returns the builtin constants computed to the requested
+// precision rather than rounded from their float64 values.
+func big_const(name string, prec uint) (*big.Float, bool) {
+	wp := prec + guard_bits
+	sqrt := func(x *big.Float) *big.Float {
+		return new_big_float(wp).Sqrt(x)
+	}
+	phi := func() *big.Float {
+		result := sqrt(new_big_float(wp).SetInt64(5))
+		result.Add(result, big.NewFloat(1))
+		return result.Quo(result, big.NewFloat(2))
+	}
+
+	var result *big.Float
+
+	switch name {
+	case "e":
+		result = big_exp(big.NewFloat(1), wp)
+	case "pi":
+		result = big_pi(wp)
+	case "tau":
+		result = big_pi(wp)
+		result.Mul(result, big.NewFloat(2))
+	case "phi":
+		result = phi()
+	case "sqrt_2":
+		result = sqrt(new_big_float(wp).SetInt64(2))
+	case "sqrt_e":
+		result = sqrt(big_exp(big.NewFloat(1), wp))
+	case "sqrt_pi":
+		result = sqrt(big_pi(wp))
+	case "sqrt_phi":
+		result = sqrt(phi())
+	case "ln_2":
+		result = big_ln2(wp)
+	case "ln_10":
+		result = big_log(new_big_float(wp).SetInt64(10), wp)
+	default:
+		return nil, false
+	}
+
+	return new_big_float(prec).Set(result), true
+}
+
+func rat_floor(r *big.Rat) *big.Int {
+	// big.Int.Div rounds towards negative infinity for positive divisors
+	return new(big.Int).Div(r.Num(), r.Denom())
+}
+
+func rat_ceil(r *big.Rat) *big.Int {
+	result := rat_floor(r)
+	if !r.IsInt() {
+		result.Add(result, big.NewInt(1))
+	}
+
+	return result
+}
+
+func rat_trunc(r *big.Rat) *big.Int {
+	return new(big.Int).Quo(r.Num(), r.Denom())
+}
+
+// max_round_digits bounds the decimal digits rat_round computes a power of
+// ten for, rationals that do not terminate are returned as they are when
+// rounded to more digits than that.
+const max_round_digits = 1 << 16
+
+// rat_round rounds half away from zero to the given number of decimal digits,
+// the same way math.Round does for zero digits.
+func rat_round(r *big.Rat, digits int64) *big.Rat {
+	if places, exact := r.FloatPrec(); (exact && int64(places) <= digits) || digits > max_round_digits {
+		return r
+	}
+
+	// |r| is below 2^bits, so it rounds to zero at any place from 10^bits on
+	if bits := r.Num().BitLen() - r.Denom().BitLen() + 1; digits < 0 && digits <= -int64(bits) {
+		return new(big.Rat)
+	}
+
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(abs_int64(digits)), nil))
+	if digits < 0 {
+		scale.Inv(scale)
+	}
+
+	scaled := new(big.Rat).Mul(r, scale)
+	half := big.NewRat(1, 2)
+	if scaled.Sign() < 0 {
+		half.Neg(half)
+	}
+	scaled.Add(scaled, half)
+
+	result := new(big.Rat).SetInt(rat_trunc(scaled))
+	return result.Quo(result, scale)
+}
+
+func is_finite(value float64) bool {
+	return !math.IsInf(value, 0) && !math.IsNaN(value)
+}
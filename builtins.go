@@ -1,27 +1,266 @@
-package main
+package calc
 
 import (
 	"fmt"
 	"math"
+	"math/big"
+	"math/cmplx"
+	"math/rand/v2"
+	"slices"
+	"strconv"
 )
 
-var builtin_consts = map[string]Float64Object{
-	"e":        {math.E},
-	"pi":       {math.Pi},
-	"phi":      {math.Phi},
-	"sqrt_2":   {math.Sqrt2},
-	"sqrt_e":   {math.SqrtE},
-	"sqrt_pi":  {math.SqrtPi},
-	"sqrt_phi": {math.SqrtPhi},
-	"ln_2":     {math.Ln2},
-	"ln_10":    {math.Ln10},
+var builtin_consts = map[string]Object{
+	"e":        Float64Object{math.E},
+	"pi":       Float64Object{math.Pi},
+	"tau":      Float64Object{2 * math.Pi},
+	"phi":      Float64Object{math.Phi},
+	"sqrt_2":   Float64Object{math.Sqrt2},
+	"sqrt_e":   Float64Object{math.SqrtE},
+	"sqrt_pi":  Float64Object{math.SqrtPi},
+	"sqrt_phi": Float64Object{math.SqrtPhi},
+	"ln_2":     Float64Object{math.Ln2},
+	"ln_10":    Float64Object{math.Ln10},
+	"inf":      Float64Object{math.Inf(1)},
+	"i":        ComplexObject{1i},
 }
 
-type BuiltinFn func(args ...Float64Object) (Float64Object, error)
+// CallContext carries the state of the running vm that builtins and
+// arithmetic may depend on.
+type CallContext struct {
+	Rand        *rand.Rand
+	NumericMode NumericMode
+	Precision   uint
+	Scale       int32
+	Rounding    RoundingMode
+	Word        Word
+	Overflow    OverflowMode
+	// Angle is the unit of the arguments of sin and friends and of the
+	// results of their inverses.
+	Angle AngleMode
+}
+
+// normalize brings results back into the representation of the numeric mode.
+// In BigMode float64 results keep the 53 bits they were computed with, so
+// they are not printed with made up digits. In DecimalMode they are rounded to
+// the configured scale and in IntegerMode they are truncated and fitted into
+// the word, which fails for overflows that are not allowed to wrap. Complex
+// values without an imaginary part become real numbers first and lists are
+// normalized element by element.
+func (ctx *CallContext) normalize(obj Object) (Object, error) {
+	obj = collapse_complex(obj)
+
+	if quantity, ok := obj.(QuantityObject); ok {
+		value, err := ctx.normalize(quantity.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return QuantityObject{value, quantity.Unit}, nil
+	}
+
+	if list, ok := obj.(ListObject); ok {
+		values := make([]Object, len(list.Values))
+		for i, value := range list.Values {
+			normalized, err := ctx.normalize(value)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = normalized
+		}
+
+		return ListObject{values}, nil
+	}
+
+	switch ctx.NumericMode {
+	case IntegerMode:
+		return to_int(ctx, obj)
+	case DecimalMode:
+		switch value := obj.(type) {
+		case Float64Object:
+			if result, ok := decimal_from_float(value.Value, ctx.Scale, ctx.Rounding); ok {
+				return result, nil
+			}
+		case BigRatObject:
+			return decimal_from_rat(value.Value, ctx.Scale, ctx.Rounding), nil
+		case DecimalObject:
+			return limit_scale(ctx, value), nil
+		}
+	case FloatMode:
+		// exact integer results of operators like & and // become floats again
+		switch obj.(type) {
+		case BigIntObject, BigRatObject:
+			return to_float64(obj)
+		}
+	case BigMode:
+		if value, ok := obj.(Float64Object); ok && is_finite(value.Value) {
+			return BigFloatObject{new_big_float(53).SetFloat64(value.Value)}, nil
+		}
+	}
+
+	return obj, nil
+}
+
+// bind brings the value of a parameter into the numeric mode. RationalMode
+// reads it like a literal of its shortest decimal digits, so 0.1 is 1/10
+// rather than the binary fraction closest to it.
+func (ctx *CallContext) bind(x float64) (Object, error) {
+	if ctx.NumericMode == RationalMode && is_finite(x) {
+		value, _ := new(big.Rat).SetString(strconv.FormatFloat(x, 'g', -1, 64))
+		return normalize_rat(value), nil
+	}
+
+	return ctx.normalize(Float64Object{x})
+}
+
+type BuiltinFn func(ctx *CallContext, args ...Float64Object) (Float64Object, error)
+
+// ObjectFn is the optional counterpart of a BuiltinFn that works on any value
+// type. It returns a nil object for arguments it does not handle, which makes
+// the call fall back to the float64 implementation.
+type ObjectFn func(ctx *CallContext, args ...Object) (Object, error)
+
+// Arity describes how many arguments a builtin function accepts. A Max of
+// variadic means the function accepts any number of arguments above Min.
+type Arity struct {
+	Min int
+	Max int
+}
+
+const variadic = -1
+
+func arity_exact(n int) Arity {
+	return Arity{Min: n, Max: n}
+}
+
+func arity_range(min, max int) Arity {
+	return Arity{Min: min, Max: max}
+}
 
+func arity_min(min int) Arity {
+	return Arity{Min: min, Max: variadic}
+}
+
+func (a Arity) Check(name string, found int) error {
+	switch {
+	case a.Max == variadic:
+		return arg_min_err(name, a.Min, found)
+	case a.Min == a.Max:
+		return arg_len_err(name, a.Min, found)
+	default:
+		return arg_range_err(name, a.Min, a.Max, found)
+	}
+}
+
+// BuiltinFnDescriptor registers a builtin under a stable pointer. Impure
+// builtins read state from their CallContext and may return a different value
+// on every call, so their calls must never be folded into constants or cached.
 type BuiltinFnDescriptor struct {
-	Pointer int
-	Fn      BuiltinFn
+	Pointer   int
+	Arity     Arity
+	Doc       string
+	Impure    bool
+	Fn        BuiltinFn
+	ObjectFn  ObjectFn
+	ComplexFn ComplexFn
+	// Lists builtins receive list arguments as they are. Variadic builtins
+	// otherwise get them spread into their arguments and the others are
+	// called once per element.
+	Lists bool
+	// UnitRoot lets a builtin take a quantity as its first argument, the
+	// result gets the UnitRoot'th root of its unit. Builtins without one
+	// reject quantities with units.
+	UnitRoot int
+}
+
+func (d BuiltinFnDescriptor) Call(ctx *CallContext, args []Object) (Object, error) {
+	if !d.Lists && any_list(args) {
+		if d.Arity.Max == variadic {
+			args = flatten_lists(args)
+		} else {
+			return d.map_list_call(ctx, args)
+		}
+	}
+
+	if any_quantity(args) {
+		return d.call_quantity(ctx, args)
+	}
+
+	// builtins without a float64 implementation only work on objects
+	if d.Fn == nil {
+		return d.call_object(ctx, args)
+	}
+
+	if d.ComplexFn != nil && any_complex(args) {
+		return d.call_complex(ctx, args)
+	}
+
+	if d.ObjectFn != nil && !all_float64(args) {
+		ret, err := d.ObjectFn(ctx, args...)
+		if err != nil {
+			return nil, err
+		}
+		if ret != nil {
+			return ctx.normalize(ret)
+		}
+	}
+
+	floats := make([]Float64Object, len(args))
+	for i, arg := range args {
+		value, err := to_float64(arg)
+		if err != nil {
+			return nil, err
+		}
+		floats[i] = value
+	}
+
+	ret, err := d.Fn(ctx, floats...)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.ComplexFn != nil && real_nan_result(ret, args) {
+		return d.call_complex(ctx, args)
+	}
+
+	return ctx.normalize(ret)
+}
+
+func (d BuiltinFnDescriptor) call_object(ctx *CallContext, args []Object) (Object, error) {
+	ret, err := d.ObjectFn(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ctx.normalize(ret)
+}
+
+func (d BuiltinFnDescriptor) call_complex(ctx *CallContext, args []Object) (Object, error) {
+	values := make([]complex128, len(args))
+	for i, arg := range args {
+		value, err := to_complex(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	ret, err := d.ComplexFn(ctx, values...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ctx.normalize(ComplexObject{ret})
+}
+
+func all_float64(args []Object) bool {
+	for _, arg := range args {
+		if _, ok := arg.(Float64Object); !ok {
+			return false
+		}
+	}
+
+	return true
 }
 
 var zero = Float64Object{0}
@@ -34,299 +273,2802 @@ func arg_len_err(name string, expected, found int) error {
 	return nil
 }
 
+func arg_min_err(name string, min, found int) error {
+	if found < min {
+		return fmt.Errorf("function '%s' expects at least %d arguments but got %d", name, min, found)
+	}
+
+	return nil
+}
+
+func arg_range_err(name string, min, max, found int) error {
+	if found < min || found > max {
+		return fmt.Errorf("function '%s' expects between %d and %d arguments but got %d", name, min, max, found)
+	}
+
+	return nil
+}
+
+// EmptyInputError is returned by the statistics builtins when they are given
+// fewer values than they need to produce a result.
+type EmptyInputError struct {
+	Name   string
+	Needed int
+	Found  int
+}
+
+func (err EmptyInputError) Error() string {
+	return fmt.Sprintf("function '%s' needs at least %d values but got %d", err.Name, err.Needed, err.Found)
+}
+
+func empty_input_err(name string, needed, found int) error {
+	if found < needed {
+		return EmptyInputError{Name: name, Needed: needed, Found: found}
+	}
+
+	return nil
+}
+
+func float_values(args []Float64Object) []float64 {
+	values := make([]float64, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	return values
+}
+
+func sorted_values(args []Float64Object) []float64 {
+	values := float_values(args)
+	slices.Sort(values)
+	return values
+}
+
+// welford computes the mean and the sum of squared deviations from the mean
+// in a single numerically stable pass.
+func welford(args []Float64Object) (mean, m2 float64) {
+	for i, arg := range args {
+		delta := arg.Value - mean
+		mean += delta / float64(i+1)
+		m2 += delta * (arg.Value - mean)
+	}
+
+	return mean, m2
+}
+
+// percentile interpolates linearly between the closest ranks of an already
+// sorted slice, p is expected to be within [0, 100].
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := math.Floor(rank)
+	upper := math.Ceil(rank)
+
+	if lower == upper {
+		return sorted[int(rank)]
+	}
+
+	return sorted[int(lower)] + (rank-lower)*(sorted[int(upper)]-sorted[int(lower)])
+}
+
+// exact_stats runs the object implementation of a statistics builtin, which
+// computes with the operators and so stays exact on rationals and precise on
+// big floats. Decimals are computed as rationals and only rounded to the
+// scale once the call returns. IntegerMode keeps the float64 implementation,
+// integer division would pick the wrong ranks and means.
+func exact_stats(fn ObjectFn) ObjectFn {
+	return func(ctx *CallContext, args ...Object) (Object, error) {
+		if ctx.NumericMode == IntegerMode {
+			return nil, nil
+		}
+
+		if ctx.NumericMode == DecimalMode {
+			exact := *ctx
+			exact.NumericMode = RationalMode
+			ctx = &exact
+
+			args = slices.Clone(args)
+			for i, arg := range args {
+				if decimal, ok := arg.(DecimalObject); ok {
+					args[i] = BigRatObject{decimal.Rat()}
+				}
+			}
+		}
+
+		return fn(ctx, args...)
+	}
+}
+
+func object_sum(ctx *CallContext, values []Object) (Object, error) {
+	sum := values[0]
+	for _, value := range values[1:] {
+		var err error
+		if sum, err = binary_op(ctx, OpAdd, sum, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return sum, nil
+}
+
+func object_mean(ctx *CallContext, values []Object) (Object, error) {
+	sum, err := object_sum(ctx, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return binary_op(ctx, OpDiv, sum, big_int(int64(len(values))))
+}
+
+// object_squared_deviations is the sum of the squared deviations of values
+// from their mean.
+func object_squared_deviations(ctx *CallContext, values []Object) (Object, error) {
+	mean, err := object_mean(ctx, values)
+	if err != nil {
+		return nil, err
+	}
+
+	squares := make([]Object, len(values))
+	for i, value := range values {
+		deviation, err := binary_op(ctx, OpSub, value, mean)
+		if err != nil {
+			return nil, err
+		}
+		if squares[i], err = binary_op(ctx, OpMul, deviation, deviation); err != nil {
+			return nil, err
+		}
+	}
+
+	return object_sum(ctx, squares)
+}
+
+func sorted_objects(ctx *CallContext, values []Object) ([]Object, error) {
+	sorted := slices.Clone(values)
+
+	var err error
+	slices.SortStableFunc(sorted, func(a, b Object) int {
+		cmp, cmp_err := compare_objects(ctx, a, b)
+		if cmp_err != nil {
+			err = cmp_err
+		}
+		return cmp
+	})
+
+	return sorted, err
+}
+
+// object_percentile is percentile on objects, p has been checked to be within
+// [0, 100].
+func object_percentile(ctx *CallContext, sorted []Object, p Object) (Object, error) {
+	rank, err := binary_op(ctx, OpMul, p, big_int(int64(len(sorted)-1)))
+	if err != nil {
+		return nil, err
+	}
+	if rank, err = binary_op(ctx, OpDiv, rank, big_int(100)); err != nil {
+		return nil, err
+	}
+
+	exact, ok := exact_rat(rank)
+	if !ok {
+		return nil, nil
+	}
+
+	lower := new(big.Int).Quo(exact.Num(), exact.Denom())
+	i := int(lower.Int64())
+	if exact.IsInt() || i >= len(sorted)-1 {
+		return sorted[i], nil
+	}
+
+	fraction, err := binary_op(ctx, OpSub, rank, BigIntObject{lower})
+	if err != nil {
+		return nil, err
+	}
+	step, err := binary_op(ctx, OpSub, sorted[i+1], sorted[i])
+	if err != nil {
+		return nil, err
+	}
+	if step, err = binary_op(ctx, OpMul, fraction, step); err != nil {
+		return nil, err
+	}
+
+	return binary_op(ctx, OpAdd, sorted[i], step)
+}
+
+// check_percentile fails for percentiles outside of [0, 100].
+func check_percentile(ctx *CallContext, p Object) error {
+	low, err := compare_objects(ctx, p, big_int(0))
+	if err != nil {
+		return err
+	}
+	high, err := compare_objects(ctx, p, big_int(100))
+	if err != nil {
+		return err
+	}
+
+	if low < 0 || high > 0 {
+		return fmt.Errorf("function 'percentile' expects a percentile between 0 and 100 but got %s", p)
+	}
+
+	return nil
+}
+
+// DomainError is returned when a builtin receives an argument it is not
+// defined for, such as a fractional or negative factorial.
+type DomainError struct {
+	Name   string
+	Reason string
+}
+
+func (err DomainError) Error() string {
+	return fmt.Sprintf("function '%s' %s", err.Name, err.Reason)
+}
+
+// max_exact_int is the largest integer a float64 holds without losing
+// precision, anything above it can no longer be treated as an integer.
+const max_exact_int = 1 << 53
+
+func int_arg(name string, arg Float64Object) (int64, error) {
+	value := arg.Value
+
+	if value != math.Trunc(value) || math.IsInf(value, 0) {
+		return 0, DomainError{Name: name, Reason: fmt.Sprintf("expects integer arguments but got %g", value)}
+	}
+
+	if math.Abs(value) > max_exact_int {
+		return 0, DomainError{Name: name, Reason: fmt.Sprintf("expects integers within ±2^53 but got %g", value)}
+	}
+
+	return int64(value), nil
+}
+
+func natural_arg(name string, arg Float64Object) (int64, error) {
+	value, err := int_arg(name, arg)
+	if err != nil {
+		return 0, err
+	}
+
+	if value < 0 {
+		return 0, DomainError{Name: name, Reason: fmt.Sprintf("expects non-negative integers but got %d", value)}
+	}
+
+	return value, nil
+}
+
+func int_args(name string, args []Float64Object) ([]int64, error) {
+	values := make([]int64, len(args))
+
+	for i, arg := range args {
+		value, err := int_arg(name, arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+func gcd(a, b int64) int64 {
+	if a < 0 {
+		a = -a
+	}
+	if b < 0 {
+		b = -b
+	}
+
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
+
+// is_prime is exact for every integer a float64 can represent, ProbablyPrime
+// is guaranteed correct below 2^64.
+func is_prime(n int64) bool {
+	return n > 1 && big.NewInt(n).ProbablyPrime(0)
+}
+
+// permutations computes n!/(n-k)! one factor at a time so intermediate values
+// stay as small as the result allows.
+func permutations(n, k int64) float64 {
+	result := 1.0
+	for i := n - k + 1; i <= n && !math.IsInf(result, 1); i++ {
+		result *= float64(i)
+	}
+
+	return result
+}
+
+func lgamma(x float64) float64 {
+	result, _ := math.Lgamma(x)
+	return result
+}
+
+// sin_quadrant returns the sine of an angle within [0, 90] degrees, using the
+// exact values where radian conversion would introduce rounding errors.
+func sin_quadrant(angle float64) float64 {
+	switch angle {
+	case 30:
+		return 0.5
+	case 45:
+		return math.Sqrt2 / 2
+	case 60:
+		return math.Sqrt(3) / 2
+	}
+
+	if angle > 45 {
+		return math.Cos((90 - angle) * (math.Pi / 180))
+	}
+
+	return math.Sin(angle * (math.Pi / 180))
+}
+
+func sin_deg(x float64) float64 {
+	if math.IsInf(x, 0) {
+		return math.NaN()
+	}
+
+	x = math.Mod(x, 360)
+	if x < 0 {
+		x += 360
+	}
+
+	quadrant := math.Floor(x / 90)
+	angle := x - quadrant*90
+
+	switch quadrant {
+	case 0:
+		return sin_quadrant(angle)
+	case 1:
+		return sin_quadrant(90 - angle)
+	case 2:
+		// subtracting from zero keeps sind(180) at +0 rather than -0
+		return 0 - sin_quadrant(angle)
+	default:
+		return 0 - sin_quadrant(90-angle)
+	}
+}
+
+func cos_deg(x float64) float64 {
+	return sin_deg(math.Mod(x, 360) + 90)
+}
+
+// binomial_coefficient multiplies and divides in lockstep, which keeps every
+// partial result an exact binomial coefficient.
+func binomial_coefficient(n, k int64) float64 {
+	k = min(k, n-k)
+	result := 1.0
+	for i := int64(1); i <= k && !math.IsInf(result, 1); i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+
+	return math.Round(result)
+}
+
+// max_exact_fact bounds the factorials computed exactly with big.Int, the
+// result of the limit already has close to half a million digits.
+const max_exact_fact = 100000
+
+func exact_natural_pair(args []Object) (int64, int64, bool) {
+	n, ok := exact_int(args[0])
+	if !ok || !n.IsInt64() || n.Sign() < 0 {
+		return 0, 0, false
+	}
+
+	k, ok := exact_int(args[1])
+	if !ok || !k.IsInt64() || k.Sign() < 0 {
+		return 0, 0, false
+	}
+
+	return n.Int64(), k.Int64(), true
+}
+
+// big_float_fn is the ObjectFn of a builtin with a big.Float implementation
+// for BigMode, fn returns nil for the arguments it leaves to the float64 one.
+func big_float_fn(fn func(ctx *CallContext, x *big.Float) *big.Float) ObjectFn {
+	return func(ctx *CallContext, args ...Object) (Object, error) {
+		x, ok := as_big_float(ctx, args[0])
+		if !ok {
+			return nil, nil
+		}
+
+		result := fn(ctx, x)
+		if result == nil {
+			return nil, nil
+		}
+
+		return BigFloatObject{result}, nil
+	}
+}
+
 type BuiltinFnList map[string]BuiltinFnDescriptor
 
-func (list BuiltinFnList) GetPointer(pointer int) *BuiltinFn {
+// name_of returns the name of the builtin at pointer.
+func (list BuiltinFnList) name_of(pointer int) string {
+	for name, descriptor := range list {
+		if descriptor.Pointer == pointer {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// table lays the descriptors out at the index of their pointer.
+func (list BuiltinFnList) table() []*BuiltinFnDescriptor {
+	size := 0
+	for _, descriptor := range list {
+		size = max(size, descriptor.Pointer+1)
+	}
+
+	table := make([]*BuiltinFnDescriptor, size)
+	for _, descriptor := range list {
+		table[descriptor.Pointer] = &descriptor
+	}
+
+	return table
+}
+
+// builtin_table resolves the pointers of calls without scanning builtin_fns.
+var builtin_table = builtin_fns.table()
+
+// builtin_at returns the builtin at pointer, or nil if there is none.
+func builtin_at(pointer int) *BuiltinFnDescriptor {
+	if pointer < 0 || pointer >= len(builtin_table) {
+		return nil
+	}
+
+	return builtin_table[pointer]
+}
+
+func (list BuiltinFnList) GetPointer(pointer int) *BuiltinFnDescriptor {
 	for _, descriptor := range list {
 		if descriptor.Pointer == pointer {
-			return &descriptor.Fn
+			return &descriptor
 		}
 	}
 
-	return nil
-}
+	return nil
+}
+
+var builtin_fns = BuiltinFnList{
+	"abs": {
+		Pointer: 0,
+		Arity:   arity_exact(1),
+		Doc:     "abs(x) returns the absolute value of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("abs", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Abs(args[0].Value)}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			return object_abs(args[0]), nil
+		},
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return complex(cmplx.Abs(args[0]), 0), nil
+		},
+		UnitRoot: 1,
+	},
+	"acos": {
+		Pointer: 1,
+		Arity:   arity_exact(1),
+		Doc:     "acos(x) returns the arccosine of x as an angle in the angle mode",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("acos", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{ctx.from_radians(math.Acos(args[0].Value))}, nil
+		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			return ctx.big_from_radians(big_acos(x, ctx.Precision))
+		}),
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return ctx.complex_from_radians(cmplx.Acos(args[0])), nil
+		},
+	},
+	"acosh": {
+		Pointer: 2,
+		Arity:   arity_exact(1),
+		Doc:     "acosh(x) returns the inverse hyperbolic cosine of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("acosh", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Acosh(args[0].Value)}, nil
+		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			return big_acosh(x, ctx.Precision)
+		}),
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return cmplx.Acosh(args[0]), nil
+		},
+	},
+	"asin": {
+		Pointer: 3,
+		Arity:   arity_exact(1),
+		Doc:     "asin(x) returns the arcsine of x as an angle in the angle mode",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("asin", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{ctx.from_radians(math.Asin(args[0].Value))}, nil
+		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			return ctx.big_from_radians(big_asin(x, ctx.Precision))
+		}),
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return ctx.complex_from_radians(cmplx.Asin(args[0])), nil
+		},
+	},
+	"asinh": {
+		Pointer: 4,
+		Arity:   arity_exact(1),
+		Doc:     "asinh(x) returns the inverse hyperbolic sine of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("asinh", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Asinh(args[0].Value)}, nil
+		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			return big_asinh(x, ctx.Precision)
+		}),
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return cmplx.Asinh(args[0]), nil
+		},
+	},
+	"atan": {
+		Pointer: 5,
+		Arity:   arity_exact(1),
+		Doc:     "atan(x) returns the arctangent of x as an angle in the angle mode",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("atan", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{ctx.from_radians(math.Atan(args[0].Value))}, nil
+		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			return ctx.big_from_radians(big_atan(x, ctx.Precision))
+		}),
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return ctx.complex_from_radians(cmplx.Atan(args[0])), nil
+		},
+	},
+	"atanh": {
+		Pointer: 6,
+		Arity:   arity_exact(1),
+		Doc:     "atanh(x) returns the inverse hyperbolic tangent of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("atanh", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Atanh(args[0].Value)}, nil
+		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			return big_atanh(x, ctx.Precision)
+		}),
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return cmplx.Atanh(args[0]), nil
+		},
+	},
+	"cbrt": {
+		Pointer: 7,
+		Arity:   arity_exact(1),
+		Doc:     "cbrt(x) returns the cube root of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("cbrt", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Cbrt(args[0].Value)}, nil
+		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			return big_cbrt(x, ctx.Precision)
+		}),
+		UnitRoot: 3,
+	},
+	"ceil": {
+		Pointer: 8,
+		Arity:   arity_exact(1),
+		Doc:     "ceil(x) returns the least integer greater than or equal to x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("ceil", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Ceil(args[0].Value)}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			if r, ok := exact_rat(args[0]); ok {
+				return BigIntObject{rat_ceil(r)}, nil
+			}
+
+			return nil, nil
+		},
+		UnitRoot: 1,
+	},
+	"cos": {
+		Pointer: 9,
+		Arity:   arity_exact(1),
+		Doc:     "cos(x) returns the cosine of the angle x in the angle mode",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("cos", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{ctx.angle_cos(args[0].Value)}, nil
+		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			_, cos := ctx.big_sin_cos(x)
+			return cos
+		}),
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return cmplx.Cos(ctx.complex_to_radians(args[0])), nil
+		},
+	},
+	"cosh": {
+		Pointer: 10,
+		Arity:   arity_exact(1),
+		Doc:     "cosh(x) returns the hyperbolic cosine of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("cosh", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Cosh(args[0].Value)}, nil
+		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			return big_cosh(x, ctx.Precision)
+		}),
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return cmplx.Cosh(args[0]), nil
+		},
+	},
+	"exp": {
+		Pointer: 11,
+		Arity:   arity_exact(1),
+		Doc:     "exp(x) returns e raised to the power of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("exp", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Exp(args[0].Value)}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			x, ok := as_big_float(ctx, args[0])
+			if !ok {
+				return nil, nil
+			}
+
+			return BigFloatObject{big_exp(x, ctx.Precision)}, nil
+		},
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return cmplx.Exp(args[0]), nil
+		},
+	},
+	"expm1": {
+		Pointer: 12,
+		Arity:   arity_exact(1),
+		Doc:     "expm1(x) returns exp(x) - 1, accurate for x near zero",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("expm1", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Expm1(args[0].Value)}, nil
+		},
+	},
+	"floor": {
+		Pointer: 13,
+		Arity:   arity_exact(1),
+		Doc:     "floor(x) returns the greatest integer less than or equal to x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("floor", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Floor(args[0].Value)}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			if r, ok := exact_rat(args[0]); ok {
+				return BigIntObject{rat_floor(r)}, nil
+			}
+
+			return nil, nil
+		},
+		UnitRoot: 1,
+	},
+	"log": {
+		Pointer: 14,
+		Arity:   arity_range(1, 2),
+		Doc:     "log(x, [base]) returns the logarithm of x, natural unless a base is given",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_range_err("log", 1, 2, len(args)); err != nil {
+				return zero, err
+			}
+
+			if len(args) == 2 {
+				return Float64Object{math.Log(args[0].Value) / math.Log(args[1].Value)}, nil
+			}
+
+			return Float64Object{math.Log(args[0].Value)}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			x, ok := as_big_float(ctx, args[0])
+			if !ok || x.Sign() <= 0 {
+				return nil, nil
+			}
+
+			result := big_log(x, ctx.Precision+guard_bits)
+
+			if len(args) == 2 {
+				base, ok := as_big_float(ctx, args[1])
+				if !ok || base.Sign() <= 0 || base.Cmp(big.NewFloat(1)) == 0 {
+					return nil, nil
+				}
+				result.Quo(result, big_log(base, ctx.Precision+guard_bits))
+			}
+
+			return BigFloatObject{new_big_float(ctx.Precision).Set(result)}, nil
+		},
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			if len(args) == 2 {
+				return cmplx.Log(args[0]) / cmplx.Log(args[1]), nil
+			}
+
+			return cmplx.Log(args[0]), nil
+		},
+	},
+	"log10": {
+		Pointer: 15,
+		Arity:   arity_exact(1),
+		Doc:     "log10(x) returns the decimal logarithm of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("log10", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Log10(args[0].Value)}, nil
+		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			if x.Sign() <= 0 {
+				return nil
+			}
+
+			return big_log_base(x, 10, ctx.Precision)
+		}),
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return cmplx.Log10(args[0]), nil
+		},
+	},
+	"log1p": {
+		Pointer: 16,
+		Arity:   arity_exact(1),
+		Doc:     "log1p(x) returns log(1 + x), accurate for x near zero",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("log1p", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Log1p(args[0].Value)}, nil
+		},
+	},
+	"log2": {
+		Pointer: 17,
+		Arity:   arity_exact(1),
+		Doc:     "log2(x) returns the binary logarithm of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("log2", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Log2(args[0].Value)}, nil
+		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			if x.Sign() <= 0 {
+				return nil
+			}
+
+			return big_log_base(x, 2, ctx.Precision)
+		}),
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return cmplx.Log(args[0]) / math.Ln2, nil
+		},
+	},
+	"round": {
+		Pointer: 18,
+		Arity:   arity_range(1, 2),
+		Doc:     "round(x, [digits]) rounds x to the given number of decimal digits, half away from zero or with the rounding mode of decimals",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_range_err("round", 1, 2, len(args)); err != nil {
+				return zero, err
+			}
+
+			if len(args) == 2 {
+				x := args[0].Value
+				scale := math.Pow(10, math.Trunc(args[1].Value))
+				if scale == 0 {
+					return Float64Object{0}, nil
+				}
+
+				// past 2^52 every float64 is an integer, so x has no digits at
+				// the place to round away, and the place may not even be finite
+				scaled := x * scale
+				if !is_finite(scaled) || math.Abs(scaled) >= 1<<52 {
+					return args[0], nil
+				}
+
+				return Float64Object{math.Round(scaled) / scale}, nil
+			}
+
+			return Float64Object{math.Round(args[0].Value)}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			r, ok := exact_rat(args[0])
+			if !ok {
+				return nil, nil
+			}
+
+			digits := int64(0)
+			if len(args) == 2 {
+				n, ok := exact_int(args[1])
+				if !ok || !n.IsInt64() {
+					return nil, nil
+				}
+				digits = n.Int64()
+			}
+
+			// decimals round with the configured rounding mode
+			if value, ok := args[0].(DecimalObject); ok && digits >= math.MinInt32 && digits <= math.MaxInt32 {
+				return decimal_round(ctx, value, int32(digits)), nil
+			}
+
+			return normalize_rat(rat_round(r, digits)), nil
+		},
+		UnitRoot: 1,
+	},
+	"sin": {
+		Pointer: 19,
+		Arity:   arity_exact(1),
+		Doc:     "sin(x) returns the sine of the angle x in the angle mode",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("sin", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{ctx.angle_sin(args[0].Value)}, nil
+		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			sin, _ := ctx.big_sin_cos(x)
+			return sin
+		}),
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return cmplx.Sin(ctx.complex_to_radians(args[0])), nil
+		},
+	},
+	"sinh": {
+		Pointer: 20,
+		Arity:   arity_exact(1),
+		Doc:     "sinh(x) returns the hyperbolic sine of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("sinh", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Sinh(args[0].Value)}, nil
+		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			return big_sinh(x, ctx.Precision)
+		}),
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return cmplx.Sinh(args[0]), nil
+		},
+	},
+	"sqrt": {
+		Pointer: 21,
+		Arity:   arity_exact(1),
+		Doc:     "sqrt(x) returns the square root of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("sqrt", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Sqrt(args[0].Value)}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			if root, ok := exact_sqrt(args[0]); ok {
+				return root, nil
+			}
+
+			x, ok := as_big_float(ctx, args[0])
+			if !ok || x.Sign() < 0 {
+				return nil, nil
+			}
+
+			return BigFloatObject{new_big_float(ctx.Precision).Sqrt(x)}, nil
+		},
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return cmplx.Sqrt(args[0]), nil
+		},
+		UnitRoot: 2,
+	},
+	"tan": {
+		Pointer: 22,
+		Arity:   arity_exact(1),
+		Doc:     "tan(x) returns the tangent of the angle x in the angle mode",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("tan", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{ctx.angle_tan(args[0].Value)}, nil
+		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			sin, cos := ctx.big_sin_cos(x)
+			if sin == nil {
+				return nil
+			}
+
+			return sin.Quo(sin, cos)
+		}),
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return cmplx.Tan(ctx.complex_to_radians(args[0])), nil
+		},
+	},
+	"tanh": {
+		Pointer: 23,
+		Arity:   arity_exact(1),
+		Doc:     "tanh(x) returns the hyperbolic tangent of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("tanh", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Tanh(args[0].Value)}, nil
+		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			return big_tanh(x, ctx.Precision)
+		}),
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return cmplx.Tanh(args[0]), nil
+		},
+	},
+	"trunc": {
+		Pointer: 24,
+		Arity:   arity_exact(1),
+		Doc:     "trunc(x) returns the integer part of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("trunc", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Trunc(args[0].Value)}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			if r, ok := exact_rat(args[0]); ok {
+				return BigIntObject{rat_trunc(r)}, nil
+			}
+
+			return nil, nil
+		},
+		UnitRoot: 1,
+	},
+	"rad": {
+		Pointer: 25,
+		Arity:   arity_exact(1),
+		Doc:     "rad(x) converts x from degrees to radians",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("rad", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			rad := args[0].Value * (math.Pi / 180)
+			return Float64Object{rad}, nil
+		},
+	},
+	"deg": {
+		Pointer: 26,
+		Arity:   arity_exact(1),
+		Doc:     "deg(x) converts x from radians to degrees",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("deg", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			deg := args[0].Value / (math.Pi / 180)
+			return Float64Object{deg}, nil
+		},
+	},
+	"neg": {
+		Pointer: 27,
+		Arity:   arity_exact(1),
+		Doc:     "neg(x) returns the negation of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("deg", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{0 - args[0].Value}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			return object_neg(args[0]), nil
+		},
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return -args[0], nil
+		},
+		UnitRoot: 1,
+	},
+	"atan2": {
+		Pointer: 28,
+		Arity:   arity_exact(2),
+		Doc:     "atan2(y, x) returns the arctangent of y/x as an angle in the angle mode, using the signs of both to pick the quadrant",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("atan2", 2, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{ctx.from_radians(math.Atan2(args[0].Value, args[1].Value))}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			y, ok := as_big_float(ctx, args[0])
+			if !ok {
+				return nil, nil
+			}
+			x, ok := as_big_float(ctx, args[1])
+			if !ok {
+				return nil, nil
+			}
+
+			result := ctx.big_from_radians(big_atan2(y, x, ctx.Precision))
+			if result == nil {
+				return nil, nil
+			}
+
+			return BigFloatObject{result}, nil
+		},
+	},
+	"hypot": {
+		Pointer: 29,
+		Arity:   arity_min(1),
+		Doc:     "hypot(x...) returns the euclidean norm of its arguments without undue overflow",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_min_err("hypot", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			result := 0.0
+			for _, arg := range args {
+				result = math.Hypot(result, arg.Value)
+			}
+
+			return Float64Object{result}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			wp := ctx.Precision + guard_bits
+			sum := new_big_float(wp)
+			for _, arg := range args {
+				x, ok := as_big_float(ctx, arg)
+				if !ok || x.IsInf() {
+					return nil, nil
+				}
+				sum.Add(sum, new_big_float(wp).Mul(x, x))
+			}
+
+			return BigFloatObject{new_big_float(ctx.Precision).Sqrt(sum)}, nil
+		},
+	},
+	"pow": {
+		Pointer: 30,
+		Arity:   arity_exact(2),
+		Doc:     "pow(x, y) returns x raised to the power of y",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("pow", 2, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Pow(args[0].Value, args[1].Value)}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			return binary_op(ctx, OpPow, args[0], args[1])
+		},
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return complex_pow(args[0], args[1]), nil
+		},
+	},
+	"root": {
+		Pointer: 31,
+		Arity:   arity_exact(2),
+		Doc:     "root(x, n) returns the nth root of x, real for odd roots of negative numbers",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("root", 2, len(args)); err != nil {
+				return zero, err
+			}
+
+			x, n := args[0].Value, args[1].Value
+
+			switch n {
+			case 2:
+				return Float64Object{math.Sqrt(x)}, nil
+			case 3:
+				return Float64Object{math.Cbrt(x)}, nil
+			}
+
+			// odd roots of negative numbers are real, math.Pow would give NaN
+			if x < 0 && n == math.Trunc(n) && math.Mod(n, 2) != 0 {
+				return Float64Object{-math.Pow(-x, 1/n)}, nil
+			}
+
+			return Float64Object{math.Pow(x, 1/n)}, nil
+		},
+	},
+	"min": {
+		Pointer: 32,
+		Arity:   arity_min(1),
+		Doc:     "min(x...) returns the smallest of its arguments",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_min_err("min", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			result := args[0].Value
+			for _, arg := range args[1:] {
+				result = math.Min(result, arg.Value)
+			}
+
+			return Float64Object{result}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			result := args[0]
+			for _, arg := range args[1:] {
+				cmp, err := compare_objects(ctx, arg, result)
+				if err != nil {
+					return nil, err
+				}
+				if cmp < 0 {
+					result = arg
+				}
+			}
+
+			return result, nil
+		},
+	},
+	"max": {
+		Pointer: 33,
+		Arity:   arity_min(1),
+		Doc:     "max(x...) returns the largest of its arguments",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_min_err("max", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			result := args[0].Value
+			for _, arg := range args[1:] {
+				result = math.Max(result, arg.Value)
+			}
+
+			return Float64Object{result}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			result := args[0]
+			for _, arg := range args[1:] {
+				cmp, err := compare_objects(ctx, arg, result)
+				if err != nil {
+					return nil, err
+				}
+				if cmp > 0 {
+					result = arg
+				}
+			}
+
+			return result, nil
+		},
+	},
+	"sum": {
+		Pointer: 34,
+		Arity:   arity_min(0),
+		Doc:     "sum(x...) returns the compensated sum of its arguments",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			// Neumaier summation, keeps long argument lists from drifting
+			sum, compensation := 0.0, 0.0
+			for _, arg := range args {
+				t := sum + arg.Value
+				if math.Abs(sum) >= math.Abs(arg.Value) {
+					compensation += (sum - t) + arg.Value
+				} else {
+					compensation += (arg.Value - t) + sum
+				}
+				sum = t
+			}
+
+			return Float64Object{sum + compensation}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			result := args[0]
+			for _, arg := range args[1:] {
+				var err error
+				if result, err = binary_op(ctx, OpAdd, result, arg); err != nil {
+					return nil, err
+				}
+			}
+
+			return result, nil
+		},
+	},
+	"clamp": {
+		Pointer: 35,
+		Arity:   arity_exact(3),
+		Doc:     "clamp(x, lo, hi) limits x to the range [lo, hi]",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("clamp", 3, len(args)); err != nil {
+				return zero, err
+			}
+
+			x, lo, hi := args[0].Value, args[1].Value, args[2].Value
+			if lo > hi {
+				return zero, fmt.Errorf("function 'clamp' expects the lower bound %g to be less than the upper bound %g", lo, hi)
+			}
+
+			return Float64Object{math.Min(math.Max(x, lo), hi)}, nil
+		},
+		ObjectFn: exact_stats(func(ctx *CallContext, args ...Object) (Object, error) {
+			x, lo, hi := args[0], args[1], args[2]
+			if cmp, err := compare_objects(ctx, lo, hi); err != nil || cmp > 0 {
+				if err != nil {
+					return nil, err
+				}
+				return nil, fmt.Errorf("function 'clamp' expects the lower bound %s to be less than the upper bound %s", lo, hi)
+			}
+
+			if cmp, err := compare_objects(ctx, x, lo); err != nil || cmp < 0 {
+				return lo, err
+			}
+			if cmp, err := compare_objects(ctx, x, hi); err != nil || cmp > 0 {
+				return hi, err
+			}
+
+			return x, nil
+		}),
+	},
+	"lerp": {
+		Pointer: 36,
+		Arity:   arity_exact(3),
+		Doc:     "lerp(a, b, t) interpolates linearly between a and b",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("lerp", 3, len(args)); err != nil {
+				return zero, err
+			}
+
+			a, b, t := args[0].Value, args[1].Value, args[2].Value
+			return Float64Object{a + (b-a)*t}, nil
+		},
+		ObjectFn: exact_stats(func(ctx *CallContext, args ...Object) (Object, error) {
+			step, err := binary_op(ctx, OpSub, args[1], args[0])
+			if err != nil {
+				return nil, err
+			}
+			if step, err = binary_op(ctx, OpMul, step, args[2]); err != nil {
+				return nil, err
+			}
+
+			return binary_op(ctx, OpAdd, args[0], step)
+		}),
+	},
+	"sign": {
+		Pointer: 37,
+		Arity:   arity_exact(1),
+		Doc:     "sign(x) returns -1, 0 or 1 depending on the sign of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("sign", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			switch x := args[0].Value; {
+			case x > 0:
+				return Float64Object{1}, nil
+			case x < 0:
+				return Float64Object{-1}, nil
+			default:
+				return Float64Object{x}, nil
+			}
+		},
+	},
+	"mean": {
+		Pointer: 38,
+		Arity:   arity_min(0),
+		Doc:     "mean(x...) returns the arithmetic mean of its arguments",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := empty_input_err("mean", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			mean, _ := welford(args)
+			return Float64Object{mean}, nil
+		},
+		ObjectFn: exact_stats(func(ctx *CallContext, args ...Object) (Object, error) {
+			if err := empty_input_err("mean", 1, len(args)); err != nil {
+				return nil, err
+			}
+
+			return object_mean(ctx, args)
+		}),
+	},
+	"median": {
+		Pointer: 39,
+		Arity:   arity_min(0),
+		Doc:     "median(x...) returns the middle value of its arguments",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := empty_input_err("median", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{percentile(sorted_values(args), 50)}, nil
+		},
+		ObjectFn: exact_stats(func(ctx *CallContext, args ...Object) (Object, error) {
+			if err := empty_input_err("median", 1, len(args)); err != nil {
+				return nil, err
+			}
+
+			sorted, err := sorted_objects(ctx, args)
+			if err != nil {
+				return nil, err
+			}
+
+			return object_percentile(ctx, sorted, big_int(50))
+		}),
+	},
+	"mode": {
+		Pointer: 40,
+		Arity:   arity_min(0),
+		Doc:     "mode(x...) returns the most frequent argument, the smallest one on ties",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := empty_input_err("mode", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			// values are sorted so equal values are adjacent and ties resolve
+			// to the smallest value
+			values := sorted_values(args)
+			result, best, run := values[0], 1, 1
+			for i := 1; i < len(values); i++ {
+				if values[i] == values[i-1] {
+					run++
+				} else {
+					run = 1
+				}
+
+				if run > best {
+					result, best = values[i], run
+				}
+			}
+
+			return Float64Object{result}, nil
+		},
+		ObjectFn: exact_stats(func(ctx *CallContext, args ...Object) (Object, error) {
+			if err := empty_input_err("mode", 1, len(args)); err != nil {
+				return nil, err
+			}
+
+			values, err := sorted_objects(ctx, args)
+			if err != nil {
+				return nil, err
+			}
+
+			result, best, run := values[0], 1, 1
+			for i := 1; i < len(values); i++ {
+				if cmp, _ := compare_objects(ctx, values[i], values[i-1]); cmp == 0 {
+					run++
+				} else {
+					run = 1
+				}
+
+				if run > best {
+					result, best = values[i], run
+				}
+			}
+
+			return result, nil
+		}),
+	},
+	"variance": {
+		Pointer: 41,
+		Arity:   arity_min(0),
+		Doc:     "variance(x...) returns the population variance of its arguments",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := empty_input_err("variance", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			_, m2 := welford(args)
+			return Float64Object{m2 / float64(len(args))}, nil
+		},
+		ObjectFn: exact_stats(func(ctx *CallContext, args ...Object) (Object, error) {
+			if err := empty_input_err("variance", 1, len(args)); err != nil {
+				return nil, err
+			}
+
+			m2, err := object_squared_deviations(ctx, args)
+			if err != nil {
+				return nil, err
+			}
+
+			return binary_op(ctx, OpDiv, m2, big_int(int64(len(args))))
+		}),
+	},
+	"svariance": {
+		Pointer: 42,
+		Arity:   arity_min(0),
+		Doc:     "svariance(x...) returns the sample variance of its arguments",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := empty_input_err("svariance", 2, len(args)); err != nil {
+				return zero, err
+			}
+
+			_, m2 := welford(args)
+			return Float64Object{m2 / float64(len(args)-1)}, nil
+		},
+		ObjectFn: exact_stats(func(ctx *CallContext, args ...Object) (Object, error) {
+			if err := empty_input_err("svariance", 2, len(args)); err != nil {
+				return nil, err
+			}
+
+			m2, err := object_squared_deviations(ctx, args)
+			if err != nil {
+				return nil, err
+			}
+
+			return binary_op(ctx, OpDiv, m2, big_int(int64(len(args)-1)))
+		}),
+	},
+	"stddev": {
+		Pointer: 43,
+		Arity:   arity_min(0),
+		Doc:     "stddev(x...) returns the population standard deviation of its arguments",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := empty_input_err("stddev", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			_, m2 := welford(args)
+			return Float64Object{math.Sqrt(m2 / float64(len(args)))}, nil
+		},
+	},
+	"sstddev": {
+		Pointer: 44,
+		Arity:   arity_min(0),
+		Doc:     "sstddev(x...) returns the sample standard deviation of its arguments",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := empty_input_err("sstddev", 2, len(args)); err != nil {
+				return zero, err
+			}
+
+			_, m2 := welford(args)
+			return Float64Object{math.Sqrt(m2 / float64(len(args)-1))}, nil
+		},
+	},
+	"percentile": {
+		Pointer: 45,
+		Arity:   arity_min(1),
+		Doc:     "percentile(p, x...) returns the pth percentile of the values, interpolating between ranks",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_min_err("percentile", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			p := args[0].Value
+			if p < 0 || p > 100 || math.IsNaN(p) {
+				return zero, fmt.Errorf("function 'percentile' expects a percentile between 0 and 100 but got %g", p)
+			}
+
+			if err := empty_input_err("percentile", 1, len(args)-1); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{percentile(sorted_values(args[1:]), p)}, nil
+		},
+		ObjectFn: exact_stats(func(ctx *CallContext, args ...Object) (Object, error) {
+			if err := arg_min_err("percentile", 1, len(args)); err != nil {
+				return nil, err
+			}
+
+			if err := check_percentile(ctx, args[0]); err != nil {
+				return nil, err
+			}
+
+			if err := empty_input_err("percentile", 1, len(args)-1); err != nil {
+				return nil, err
+			}
+
+			sorted, err := sorted_objects(ctx, args[1:])
+			if err != nil {
+				return nil, err
+			}
+
+			return object_percentile(ctx, sorted, args[0])
+		}),
+	},
+	"geomean": {
+		Pointer: 46,
+		Arity:   arity_min(0),
+		Doc:     "geomean(x...) returns the geometric mean of its positive arguments",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := empty_input_err("geomean", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			// averaging logarithms avoids overflowing the running product
+			sum := 0.0
+			for _, arg := range args {
+				if arg.Value <= 0 {
+					return zero, fmt.Errorf("function 'geomean' expects positive values but got %g", arg.Value)
+				}
+				sum += math.Log(arg.Value)
+			}
+
+			return Float64Object{math.Exp(sum / float64(len(args)))}, nil
+		},
+	},
+	"fact": {
+		Pointer: 47,
+		Arity:   arity_exact(1),
+		Doc:     "fact(n) returns the factorial of n, also written as n!",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("fact", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			n, err := natural_arg("fact", args[0])
+			if err != nil {
+				return zero, err
+			}
+
+			return Float64Object{permutations(n, n)}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			n, ok := exact_int(args[0])
+			if !ok {
+				return nil, nil
+			}
+
+			if n.Sign() < 0 {
+				return nil, DomainError{Name: "fact", Reason: fmt.Sprintf("expects non-negative integers but got %s", n)}
+			}
+			if n.Cmp(big.NewInt(max_exact_fact)) > 0 {
+				return nil, DomainError{Name: "fact", Reason: fmt.Sprintf("is limited to integers up to %d but got %s", max_exact_fact, n)}
+			}
+
+			return BigIntObject{new(big.Int).MulRange(1, n.Int64())}, nil
+		},
+	},
+	"nCr": {
+		Pointer: 48,
+		Arity:   arity_exact(2),
+		Doc:     "nCr(n, k) returns the number of ways to choose k items out of n",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("nCr", 2, len(args)); err != nil {
+				return zero, err
+			}
+
+			n, err := natural_arg("nCr", args[0])
+			if err != nil {
+				return zero, err
+			}
+			k, err := natural_arg("nCr", args[1])
+			if err != nil {
+				return zero, err
+			}
+
+			if k > n {
+				return zero, nil
+			}
+
+			return Float64Object{binomial_coefficient(n, k)}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			n, k, ok := exact_natural_pair(args)
+			if !ok {
+				return nil, nil
+			}
+
+			if k > n {
+				return big_int(0), nil
+			}
+
+			return BigIntObject{new(big.Int).Binomial(n, k)}, nil
+		},
+	},
+	"nPr": {
+		Pointer: 49,
+		Arity:   arity_exact(2),
+		Doc:     "nPr(n, k) returns the number of ordered arrangements of k items out of n",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("nPr", 2, len(args)); err != nil {
+				return zero, err
+			}
+
+			n, err := natural_arg("nPr", args[0])
+			if err != nil {
+				return zero, err
+			}
+			k, err := natural_arg("nPr", args[1])
+			if err != nil {
+				return zero, err
+			}
+
+			if k > n {
+				return zero, nil
+			}
+
+			return Float64Object{permutations(n, k)}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			n, k, ok := exact_natural_pair(args)
+			if !ok {
+				return nil, nil
+			}
+
+			if k > n {
+				return big_int(0), nil
+			}
+
+			return BigIntObject{new(big.Int).MulRange(n-k+1, n)}, nil
+		},
+	},
+	"gcd": {
+		Pointer: 50,
+		Arity:   arity_min(1),
+		Doc:     "gcd(n...) returns the greatest common divisor of its arguments",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_min_err("gcd", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			values, err := int_args("gcd", args)
+			if err != nil {
+				return zero, err
+			}
+
+			result := int64(0)
+			for _, value := range values {
+				result = gcd(result, value)
+			}
+
+			return Float64Object{float64(result)}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			result := new(big.Int)
+			for _, arg := range args {
+				n, ok := exact_int(arg)
+				if !ok {
+					return nil, nil
+				}
+				result.GCD(nil, nil, result, new(big.Int).Abs(n))
+			}
+
+			return BigIntObject{result}, nil
+		},
+	},
+	"lcm": {
+		Pointer: 51,
+		Arity:   arity_min(1),
+		Doc:     "lcm(n...) returns the least common multiple of its arguments",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_min_err("lcm", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			values, err := int_args("lcm", args)
+			if err != nil {
+				return zero, err
+			}
+
+			result := 1.0
+			for _, value := range values {
+				if value == 0 {
+					return zero, nil
+				}
+
+				divisor := gcd(int64(result), value)
+				result = math.Abs(result / float64(divisor) * float64(value))
+				if result > max_exact_int {
+					return zero, DomainError{Name: "lcm", Reason: "overflows the range of exact integers"}
+				}
+			}
+
+			return Float64Object{result}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			result := big.NewInt(1)
+			for _, arg := range args {
+				n, ok := exact_int(arg)
+				if !ok {
+					return nil, nil
+				}
+				if n.Sign() == 0 {
+					return big_int(0), nil
+				}
+
+				n = new(big.Int).Abs(n)
+				divisor := new(big.Int).GCD(nil, nil, result, n)
+				result.Mul(result.Quo(result, divisor), n)
+			}
+
+			return BigIntObject{result}, nil
+		},
+	},
+	"isprime": {
+		Pointer: 52,
+		Arity:   arity_exact(1),
+		Doc:     "isprime(n) returns 1 if n is prime and 0 otherwise",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("isprime", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			n, err := int_arg("isprime", args[0])
+			if err != nil {
+				return zero, err
+			}
+
+			if is_prime(n) {
+				return Float64Object{1}, nil
+			}
+
+			return zero, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			n, ok := exact_int(args[0])
+			if !ok {
+				return nil, nil
+			}
+
+			if n.Sign() > 0 && n.ProbablyPrime(20) {
+				return big_int(1), nil
+			}
+
+			return big_int(0), nil
+		},
+	},
+	"nextprime": {
+		Pointer: 53,
+		Arity:   arity_exact(1),
+		Doc:     "nextprime(n) returns the smallest prime greater than n",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("nextprime", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			n, err := int_arg("nextprime", args[0])
+			if err != nil {
+				return zero, err
+			}
+
+			n++
+			for !is_prime(n) {
+				n++
+			}
+
+			if n > max_exact_int {
+				return zero, DomainError{Name: "nextprime", Reason: "overflows the range of exact integers"}
+			}
+
+			return Float64Object{float64(n)}, nil
+		},
+	},
+	"modpow": {
+		Pointer: 54,
+		Arity:   arity_exact(3),
+		Doc:     "modpow(b, e, m) returns b raised to e modulo m",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("modpow", 3, len(args)); err != nil {
+				return zero, err
+			}
+
+			values, err := int_args("modpow", args)
+			if err != nil {
+				return zero, err
+			}
+
+			b, e, m := values[0], values[1], values[2]
+			if m <= 0 {
+				return zero, DomainError{Name: "modpow", Reason: fmt.Sprintf("expects a positive modulus but got %d", m)}
+			}
+			if e < 0 {
+				return zero, DomainError{Name: "modpow", Reason: fmt.Sprintf("expects a non-negative exponent but got %d", e)}
+			}
+
+			result := new(big.Int).Exp(big.NewInt(b), big.NewInt(e), big.NewInt(m))
+			return Float64Object{float64(result.Int64())}, nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			values := make([]*big.Int, len(args))
+			for i, arg := range args {
+				n, ok := exact_int(arg)
+				if !ok {
+					return nil, nil
+				}
+				values[i] = n
+			}
+
+			b, e, m := values[0], values[1], values[2]
+			if m.Sign() <= 0 {
+				return nil, DomainError{Name: "modpow", Reason: fmt.Sprintf("expects a positive modulus but got %s", m)}
+			}
+			if e.Sign() < 0 {
+				return nil, DomainError{Name: "modpow", Reason: fmt.Sprintf("expects a non-negative exponent but got %s", e)}
+			}
+
+			return BigIntObject{new(big.Int).Exp(b, e, m)}, nil
+		},
+	},
+	"modinv": {
+		Pointer: 55,
+		Arity:   arity_exact(2),
+		Doc:     "modinv(a, m) returns the multiplicative inverse of a modulo m",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("modinv", 2, len(args)); err != nil {
+				return zero, err
+			}
+
+			values, err := int_args("modinv", args)
+			if err != nil {
+				return zero, err
+			}
+
+			a, m := values[0], values[1]
+			if m <= 0 {
+				return zero, DomainError{Name: "modinv", Reason: fmt.Sprintf("expects a positive modulus but got %d", m)}
+			}
+
+			result := new(big.Int).ModInverse(big.NewInt(a), big.NewInt(m))
+			if result == nil {
+				return zero, DomainError{Name: "modinv", Reason: fmt.Sprintf("has no inverse for %d modulo %d", a, m)}
+			}
+
+			return Float64Object{float64(result.Int64())}, nil
+		},
+	},
+	"gamma": {
+		Pointer: 56,
+		Arity:   arity_exact(1),
+		Doc:     "gamma(x) returns the gamma function of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("gamma", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Gamma(args[0].Value)}, nil
+		},
+	},
+	"lgamma": {
+		Pointer: 57,
+		Arity:   arity_exact(1),
+		Doc:     "lgamma(x) returns the natural logarithm of the absolute value of gamma(x)",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("lgamma", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{lgamma(args[0].Value)}, nil
+		},
+	},
+	"erf": {
+		Pointer: 58,
+		Arity:   arity_exact(1),
+		Doc:     "erf(x) returns the error function of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("erf", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Erf(args[0].Value)}, nil
+		},
+	},
+	"erfc": {
+		Pointer: 59,
+		Arity:   arity_exact(1),
+		Doc:     "erfc(x) returns the complementary error function of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("erfc", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Erfc(args[0].Value)}, nil
+		},
+	},
+	"erfinv": {
+		Pointer: 60,
+		Arity:   arity_exact(1),
+		Doc:     "erfinv(x) returns the inverse error function of x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("erfinv", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Erfinv(args[0].Value)}, nil
+		},
+	},
+	"j0": {
+		Pointer: 61,
+		Arity:   arity_exact(1),
+		Doc:     "j0(x) returns the order zero Bessel function of the first kind",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("j0", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.J0(args[0].Value)}, nil
+		},
+	},
+	"j1": {
+		Pointer: 62,
+		Arity:   arity_exact(1),
+		Doc:     "j1(x) returns the order one Bessel function of the first kind",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("j1", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.J1(args[0].Value)}, nil
+		},
+	},
+	"jn": {
+		Pointer: 63,
+		Arity:   arity_exact(2),
+		Doc:     "jn(n, x) returns the order n Bessel function of the first kind",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("jn", 2, len(args)); err != nil {
+				return zero, err
+			}
+
+			n, err := int_arg("jn", args[0])
+			if err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Jn(int(n), args[1].Value)}, nil
+		},
+	},
+	"y0": {
+		Pointer: 64,
+		Arity:   arity_exact(1),
+		Doc:     "y0(x) returns the order zero Bessel function of the second kind",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("y0", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Y0(args[0].Value)}, nil
+		},
+	},
+	"y1": {
+		Pointer: 65,
+		Arity:   arity_exact(1),
+		Doc:     "y1(x) returns the order one Bessel function of the second kind",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("y1", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Y1(args[0].Value)}, nil
+		},
+	},
+	"yn": {
+		Pointer: 66,
+		Arity:   arity_exact(2),
+		Doc:     "yn(n, x) returns the order n Bessel function of the second kind",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("yn", 2, len(args)); err != nil {
+				return zero, err
+			}
+
+			n, err := int_arg("yn", args[0])
+			if err != nil {
+				return zero, err
+			}
+
+			return Float64Object{math.Yn(int(n), args[1].Value)}, nil
+		},
+	},
+	"frexp": {
+		Pointer: 67,
+		Arity:   arity_exact(1),
+		Doc:     "frexp(x) returns the fraction of x in [0.5, 1), see frexp_exp for the exponent",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("frexp", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			frac, _ := math.Frexp(args[0].Value)
+			return Float64Object{frac}, nil
+		},
+	},
+	"frexp_exp": {
+		Pointer: 68,
+		Arity:   arity_exact(1),
+		Doc:     "frexp_exp(x) returns the power of two exponent of x, so that x = frexp(x) * 2^frexp_exp(x)",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("frexp_exp", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			_, exp := math.Frexp(args[0].Value)
+			return Float64Object{float64(exp)}, nil
+		},
+	},
+	"ldexp": {
+		Pointer: 69,
+		Arity:   arity_exact(2),
+		Doc:     "ldexp(frac, exp) returns frac * 2^exp",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("ldexp", 2, len(args)); err != nil {
+				return zero, err
+			}
 
-var builtin_fns = BuiltinFnList{
-	"abs": {
-		Pointer: 0,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("abs", 1, len(args)); err != nil {
+			exp, err := int_arg("ldexp", args[1])
+			if err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Abs(args[0].Value)}, nil
+			return Float64Object{math.Ldexp(args[0].Value, int(exp))}, nil
 		},
 	},
-	"acos": {
-		Pointer: 1,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("acos", 1, len(args)); err != nil {
+	"nextafter": {
+		Pointer: 70,
+		Arity:   arity_exact(2),
+		Doc:     "nextafter(x, y) returns the next representable value after x towards y",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("nextafter", 2, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Acos(args[0].Value)}, nil
+			return Float64Object{math.Nextafter(args[0].Value, args[1].Value)}, nil
 		},
 	},
-	"acosh": {
-		Pointer: 2,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("acosh", 1, len(args)); err != nil {
+	"sec": {
+		Pointer: 71,
+		Arity:   arity_exact(1),
+		Doc:     "sec(x) returns the secant of the angle x in the angle mode",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("sec", 1, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Acosh(args[0].Value)}, nil
+			return Float64Object{1 / ctx.angle_cos(args[0].Value)}, nil
 		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			_, cos := ctx.big_sin_cos(x)
+			if cos == nil {
+				return nil
+			}
+
+			return cos.Quo(big.NewFloat(1), cos)
+		}),
 	},
-	"asin": {
-		Pointer: 3,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("asin", 1, len(args)); err != nil {
+	"csc": {
+		Pointer: 72,
+		Arity:   arity_exact(1),
+		Doc:     "csc(x) returns the cosecant of the angle x in the angle mode",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("csc", 1, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Asin(args[0].Value)}, nil
+			return Float64Object{1 / ctx.angle_sin(args[0].Value)}, nil
 		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			sin, _ := ctx.big_sin_cos(x)
+			if sin == nil {
+				return nil
+			}
+
+			return sin.Quo(big.NewFloat(1), sin)
+		}),
 	},
-	"asinh": {
-		Pointer: 4,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("asinh", 1, len(args)); err != nil {
+	"cot": {
+		Pointer: 73,
+		Arity:   arity_exact(1),
+		Doc:     "cot(x) returns the cotangent of the angle x in the angle mode",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("cot", 1, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Asinh(args[0].Value)}, nil
+			return Float64Object{ctx.angle_cos(args[0].Value) / ctx.angle_sin(args[0].Value)}, nil
 		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			sin, cos := ctx.big_sin_cos(x)
+			if sin == nil {
+				return nil
+			}
+
+			return cos.Quo(cos, sin)
+		}),
 	},
-	"atan": {
-		Pointer: 5,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("atan", 1, len(args)); err != nil {
+	"asec": {
+		Pointer: 74,
+		Arity:   arity_exact(1),
+		Doc:     "asec(x) returns the arcsecant of x as an angle in the angle mode",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("asec", 1, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Atan(args[0].Value)}, nil
+			return Float64Object{ctx.from_radians(math.Acos(1 / args[0].Value))}, nil
 		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			return ctx.big_from_radians(big_acos(x.Quo(big.NewFloat(1), x), ctx.Precision))
+		}),
 	},
-	"atanh": {
-		Pointer: 6,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("atanh", 1, len(args)); err != nil {
+	"acsc": {
+		Pointer: 75,
+		Arity:   arity_exact(1),
+		Doc:     "acsc(x) returns the arccosecant of x as an angle in the angle mode",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("acsc", 1, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Atanh(args[0].Value)}, nil
+			return Float64Object{ctx.from_radians(math.Asin(1 / args[0].Value))}, nil
 		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			return ctx.big_from_radians(big_asin(x.Quo(big.NewFloat(1), x), ctx.Precision))
+		}),
 	},
-	"cbrt": {
-		Pointer: 7,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("cbrt", 1, len(args)); err != nil {
+	"acot": {
+		Pointer: 76,
+		Arity:   arity_exact(1),
+		Doc:     "acot(x) returns the arccotangent of x as an angle in the angle mode",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("acot", 1, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Cbrt(args[0].Value)}, nil
+			return Float64Object{ctx.from_radians(math.Atan(1 / args[0].Value))}, nil
 		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			return ctx.big_from_radians(big_atan(x.Quo(big.NewFloat(1), x), ctx.Precision))
+		}),
 	},
-	"ceil": {
-		Pointer: 8,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("ceil", 1, len(args)); err != nil {
+	"sind": {
+		Pointer: 77,
+		Arity:   arity_exact(1),
+		Doc:     "sind(x) returns the sine of x given in degrees, exact at multiples of 30 and 45",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("sind", 1, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Ceil(args[0].Value)}, nil
+			return Float64Object{sin_deg(args[0].Value)}, nil
 		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			if x.IsInf() || x.MantExp(nil) > max_reduced_exp {
+				return nil
+			}
+
+			degrees, _ := x.Rat(nil)
+			sin, _ := big_sin_cos_degrees(degrees, ctx.Precision)
+			return sin
+		}),
 	},
-	"cos": {
-		Pointer: 9,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("cos", 1, len(args)); err != nil {
+	"cosd": {
+		Pointer: 78,
+		Arity:   arity_exact(1),
+		Doc:     "cosd(x) returns the cosine of x given in degrees, exact at multiples of 30 and 45",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("cosd", 1, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Cos(args[0].Value)}, nil
+			return Float64Object{cos_deg(args[0].Value)}, nil
 		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			if x.IsInf() || x.MantExp(nil) > max_reduced_exp {
+				return nil
+			}
+
+			degrees, _ := x.Rat(nil)
+			_, cos := big_sin_cos_degrees(degrees, ctx.Precision)
+			return cos
+		}),
 	},
-	"cosh": {
-		Pointer: 10,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("cosh", 1, len(args)); err != nil {
+	"tand": {
+		Pointer: 79,
+		Arity:   arity_exact(1),
+		Doc:     "tand(x) returns the tangent of x given in degrees",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("tand", 1, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Cosh(args[0].Value)}, nil
+			return Float64Object{sin_deg(args[0].Value) / cos_deg(args[0].Value)}, nil
 		},
+		ObjectFn: big_float_fn(func(ctx *CallContext, x *big.Float) *big.Float {
+			if x.IsInf() || x.MantExp(nil) > max_reduced_exp {
+				return nil
+			}
+
+			degrees, _ := x.Rat(nil)
+			sin, cos := big_sin_cos_degrees(degrees, ctx.Precision)
+			return sin.Quo(sin, cos)
+		}),
 	},
-	"exp": {
-		Pointer: 11,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("exp", 1, len(args)); err != nil {
+	"normpdf": {
+		Pointer: 80,
+		Arity:   arity_range(1, 3),
+		Doc:     "normpdf(x, [mu], [sigma]) returns the normal probability density at x, standard unless mu and sigma are given",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_range_err("normpdf", 1, 3, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Exp(args[0].Value)}, nil
+			mu, sigma, err := normal_params("normpdf", args)
+			if err != nil {
+				return zero, err
+			}
+
+			z := (args[0].Value - mu) / sigma
+			return Float64Object{math.Exp(-z*z/2) / (sigma * math.Sqrt(2*math.Pi))}, nil
 		},
 	},
-	"expm1": {
-		Pointer: 12,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("expm1", 1, len(args)); err != nil {
+	"normcdf": {
+		Pointer: 81,
+		Arity:   arity_range(1, 3),
+		Doc:     "normcdf(x, [mu], [sigma]) returns the normal cumulative probability up to x",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_range_err("normcdf", 1, 3, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Expm1(args[0].Value)}, nil
+			mu, sigma, err := normal_params("normcdf", args)
+			if err != nil {
+				return zero, err
+			}
+
+			// erfc keeps precision in the lower tail where 1 + erf would cancel
+			z := (args[0].Value - mu) / sigma
+			return Float64Object{math.Erfc(-z/math.Sqrt2) / 2}, nil
 		},
 	},
-	"floor": {
-		Pointer: 13,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("floor", 1, len(args)); err != nil {
+	"norminv": {
+		Pointer: 82,
+		Arity:   arity_range(1, 3),
+		Doc:     "norminv(p, [mu], [sigma]) returns the value below which the normal distribution has probability p",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_range_err("norminv", 1, 3, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Floor(args[0].Value)}, nil
+			p, err := probability_arg("norminv", args[0])
+			if err != nil {
+				return zero, err
+			}
+
+			mu, sigma, err := normal_params("norminv", args)
+			if err != nil {
+				return zero, err
+			}
+
+			return Float64Object{mu - sigma*math.Sqrt2*math.Erfcinv(2*p)}, nil
 		},
 	},
-	"log": {
-		Pointer: 14,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("log", 1, len(args)); err != nil {
+	"binompdf": {
+		Pointer: 83,
+		Arity:   arity_exact(3),
+		Doc:     "binompdf(k, n, p) returns the probability of exactly k successes in n trials with success probability p",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("binompdf", 3, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Log(args[0].Value)}, nil
+			k, n, p, err := binomial_params("binompdf", args)
+			if err != nil {
+				return zero, err
+			}
+
+			return Float64Object{binomial_pdf(k, n, p)}, nil
 		},
 	},
-	"log10": {
-		Pointer: 15,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("log10", 1, len(args)); err != nil {
+	"binomcdf": {
+		Pointer: 84,
+		Arity:   arity_exact(3),
+		Doc:     "binomcdf(k, n, p) returns the probability of at most k successes in n trials with success probability p",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("binomcdf", 3, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Log10(args[0].Value)}, nil
+			k, n, p, err := binomial_params("binomcdf", args)
+			if err != nil {
+				return zero, err
+			}
+
+			return Float64Object{binomial_cdf(k, n, p)}, nil
 		},
 	},
-	"log1p": {
-		Pointer: 16,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("log1p", 1, len(args)); err != nil {
+	"poissonpdf": {
+		Pointer: 85,
+		Arity:   arity_exact(2),
+		Doc:     "poissonpdf(k, lambda) returns the probability of exactly k events at an average rate of lambda",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("poissonpdf", 2, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Log1p(args[0].Value)}, nil
+			k, err := int_arg("poissonpdf", args[0])
+			if err != nil {
+				return zero, err
+			}
+
+			lambda, err := rate_arg("poissonpdf", args[1])
+			if err != nil {
+				return zero, err
+			}
+
+			return Float64Object{poisson_pdf(k, lambda)}, nil
 		},
 	},
-	"log2": {
-		Pointer: 17,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("log2", 1, len(args)); err != nil {
+	"poissoncdf": {
+		Pointer: 86,
+		Arity:   arity_exact(2),
+		Doc:     "poissoncdf(k, lambda) returns the probability of at most k events at an average rate of lambda",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("poissoncdf", 2, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Log2(args[0].Value)}, nil
+			k, err := int_arg("poissoncdf", args[0])
+			if err != nil {
+				return zero, err
+			}
+
+			lambda, err := rate_arg("poissoncdf", args[1])
+			if err != nil {
+				return zero, err
+			}
+
+			return Float64Object{poisson_cdf(k, lambda)}, nil
 		},
 	},
-	"round": {
-		Pointer: 18,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("round", 1, len(args)); err != nil {
+	"exppdf": {
+		Pointer: 87,
+		Arity:   arity_exact(2),
+		Doc:     "exppdf(x, lambda) returns the exponential probability density at x for the rate lambda",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("exppdf", 2, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Round(args[0].Value)}, nil
+			lambda, err := rate_arg("exppdf", args[1])
+			if err != nil {
+				return zero, err
+			}
+
+			x := args[0].Value
+			if x < 0 {
+				return zero, nil
+			}
+
+			return Float64Object{lambda * math.Exp(-lambda*x)}, nil
 		},
 	},
-	"sin": {
-		Pointer: 19,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("sin", 1, len(args)); err != nil {
+	"expcdf": {
+		Pointer: 88,
+		Arity:   arity_exact(2),
+		Doc:     "expcdf(x, lambda) returns the exponential cumulative probability up to x for the rate lambda",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("expcdf", 2, len(args)); err != nil {
+				return zero, err
+			}
+
+			lambda, err := rate_arg("expcdf", args[1])
+			if err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Sin(args[0].Value)}, nil
+			x := args[0].Value
+			if x < 0 {
+				return zero, nil
+			}
+
+			return Float64Object{-math.Expm1(-lambda * x)}, nil
 		},
 	},
-	"sinh": {
-		Pointer: 20,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("sinh", 1, len(args)); err != nil {
+	"tcdf": {
+		Pointer: 89,
+		Arity:   arity_exact(2),
+		Doc:     "tcdf(t, df) returns the Student's t cumulative probability up to t with df degrees of freedom",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("tcdf", 2, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Sinh(args[0].Value)}, nil
+			df := args[1].Value
+			if !(df > 0) {
+				return zero, DomainError{Name: "tcdf", Reason: fmt.Sprintf("expects positive degrees of freedom but got %g", df)}
+			}
+
+			return Float64Object{student_t_cdf(args[0].Value, df)}, nil
 		},
 	},
-	"sqrt": {
-		Pointer: 21,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("sqrt", 1, len(args)); err != nil {
+	"rand": {
+		Pointer: 90,
+		Arity:   arity_exact(0),
+		Doc:     "rand() returns a uniformly distributed random number in [0, 1)",
+		Impure:  true,
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("rand", 0, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Sqrt(args[0].Value)}, nil
+			return Float64Object{ctx.Rand.Float64()}, nil
 		},
 	},
-	"tan": {
-		Pointer: 22,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("tan", 1, len(args)); err != nil {
+	"randint": {
+		Pointer: 91,
+		Arity:   arity_exact(2),
+		Doc:     "randint(a, b) returns a uniformly distributed random integer in [a, b]",
+		Impure:  true,
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("randint", 2, len(args)); err != nil {
+				return zero, err
+			}
+
+			values, err := int_args("randint", args)
+			if err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Tan(args[0].Value)}, nil
+			a, b := values[0], values[1]
+			if a > b {
+				return zero, DomainError{Name: "randint", Reason: fmt.Sprintf("expects the lower bound %d to be at most the upper bound %d", a, b)}
+			}
+
+			return Float64Object{float64(a + ctx.Rand.Int64N(b-a+1))}, nil
 		},
 	},
-	"tanh": {
-		Pointer: 23,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("tanh", 1, len(args)); err != nil {
+	"randn": {
+		Pointer: 92,
+		Arity:   arity_range(0, 2),
+		Doc:     "randn([mu], [sigma]) returns a normally distributed random number, standard unless mu and sigma are given",
+		Impure:  true,
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_range_err("randn", 0, 2, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Tanh(args[0].Value)}, nil
+			mu, sigma := 0.0, 1.0
+			if len(args) > 0 {
+				mu = args[0].Value
+			}
+			if len(args) > 1 {
+				sigma = args[1].Value
+			}
+
+			if !(sigma >= 0) {
+				return zero, DomainError{Name: "randn", Reason: fmt.Sprintf("expects a non-negative standard deviation but got %g", sigma)}
+			}
+
+			return Float64Object{mu + sigma*ctx.Rand.NormFloat64()}, nil
 		},
 	},
-	"trunc": {
-		Pointer: 24,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("trunc", 1, len(args)); err != nil {
+	"choice": {
+		Pointer: 93,
+		Arity:   arity_min(1),
+		Doc:     "choice(x...) returns one of its arguments picked uniformly at random",
+		Impure:  true,
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_min_err("choice", 1, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{math.Trunc(args[0].Value)}, nil
+			return args[ctx.Rand.IntN(len(args))], nil
 		},
 	},
-	"rad": {
-		Pointer: 25,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("rad", 1, len(args)); err != nil {
+	"arg": {
+		Pointer: 94,
+		Arity:   arity_exact(1),
+		Doc:     "arg(z) returns the argument of z, the angle to the positive real axis in the angle mode",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("arg", 1, len(args)); err != nil {
 				return zero, err
 			}
 
-			rad := args[0].Value * (math.Pi / 180)
-			return Float64Object{rad}, nil
+			return Float64Object{ctx.from_radians(math.Atan2(0, args[0].Value))}, nil
+		},
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return complex(ctx.from_radians(cmplx.Phase(args[0])), 0), nil
 		},
 	},
-	"deg": {
-		Pointer: 26,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("deg", 1, len(args)); err != nil {
+	"conj": {
+		Pointer: 95,
+		Arity:   arity_exact(1),
+		Doc:     "conj(z) returns the complex conjugate of z",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("conj", 1, len(args)); err != nil {
 				return zero, err
 			}
 
-			deg := args[0].Value / (math.Pi / 180)
-			return Float64Object{deg}, nil
+			return args[0], nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			// the conjugate of a real number is the number itself, exact
+			// values stay exact
+			if _, ok := args[0].(ComplexObject); !ok {
+				return args[0], nil
+			}
+
+			return nil, nil
+		},
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return cmplx.Conj(args[0]), nil
 		},
 	},
-	"neg": {
-		Pointer: 27,
-		Fn: func(args ...Float64Object) (Float64Object, error) {
-			if err := arg_len_err("deg", 1, len(args)); err != nil {
+	"re": {
+		Pointer: 96,
+		Arity:   arity_exact(1),
+		Doc:     "re(z) returns the real part of z",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("re", 1, len(args)); err != nil {
 				return zero, err
 			}
 
-			return Float64Object{0 - args[0].Value}, nil
+			return args[0], nil
+		},
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			if _, ok := args[0].(ComplexObject); !ok {
+				return args[0], nil
+			}
+
+			return nil, nil
+		},
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return complex(real(args[0]), 0), nil
+		},
+	},
+	"im": {
+		Pointer: 97,
+		Arity:   arity_exact(1),
+		Doc:     "im(z) returns the imaginary part of z",
+		Fn: func(ctx *CallContext, args ...Float64Object) (Float64Object, error) {
+			if err := arg_len_err("im", 1, len(args)); err != nil {
+				return zero, err
+			}
+
+			return zero, nil
+		},
+		ComplexFn: func(ctx *CallContext, args ...complex128) (complex128, error) {
+			return complex(imag(args[0]), 0), nil
+		},
+	},
+	"len": {
+		Pointer: 98,
+		Arity:   arity_exact(1),
+		Doc:     "len(v) returns the number of elements of the list v",
+		Lists:   true,
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			list, err := list_arg("len", args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			return big_int(int64(len(list.Values))), nil
+		},
+	},
+	"dot": {
+		Pointer: 99,
+		Arity:   arity_exact(2),
+		Doc:     "dot(u, v) returns the dot product of the lists u and v",
+		Lists:   true,
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			u, err := list_arg("dot", args[0])
+			if err != nil {
+				return nil, err
+			}
+			v, err := list_arg("dot", args[1])
+			if err != nil {
+				return nil, err
+			}
+
+			return dot_product(ctx, u, v)
+		},
+	},
+	"norm": {
+		Pointer: 100,
+		Arity:   arity_exact(1),
+		Doc:     "norm(v) returns the euclidean norm of the list v",
+		Lists:   true,
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			v, err := list_arg("norm", args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			return euclidean_norm(ctx, v)
+		},
+	},
+	"cross": {
+		Pointer: 101,
+		Arity:   arity_exact(2),
+		Doc:     "cross(u, v) returns the cross product of the three element lists u and v",
+		Lists:   true,
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			u, err := list_arg("cross", args[0])
+			if err != nil {
+				return nil, err
+			}
+			v, err := list_arg("cross", args[1])
+			if err != nil {
+				return nil, err
+			}
+
+			if len(u.Values) != 3 || len(v.Values) != 3 {
+				return nil, DomainError{Name: "cross", Reason: fmt.Sprintf("expects lists of three elements but got %d and %d", len(u.Values), len(v.Values))}
+			}
+
+			result := make([]Object, 3)
+			for i := range result {
+				j, k := (i+1)%3, (i+2)%3
+				left, err := binary_op(ctx, OpMul, u.Values[j], v.Values[k])
+				if err != nil {
+					return nil, err
+				}
+				right, err := binary_op(ctx, OpMul, u.Values[k], v.Values[j])
+				if err != nil {
+					return nil, err
+				}
+				if result[i], err = binary_op(ctx, OpSub, left, right); err != nil {
+					return nil, err
+				}
+			}
+
+			return ListObject{result}, nil
+		},
+	},
+	"factor": {
+		Pointer: 102,
+		Arity:   arity_exact(1),
+		Doc:     "factor(n) returns the list of prime factors of n in ascending order",
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			n, ok := exact_int(args[0])
+			if !ok {
+				return nil, DomainError{Name: "factor", Reason: fmt.Sprintf("expects integer arguments but got %s", args[0])}
+			}
+
+			if n.Sign() <= 0 || n.Cmp(factor_limit) > 0 {
+				return nil, DomainError{Name: "factor", Reason: fmt.Sprintf("expects integers between 1 and 2^64-1 but got %s", n)}
+			}
+
+			factors := []Object{}
+			for _, p := range prime_factors(n.Uint64()) {
+				factors = append(factors, BigIntObject{new(big.Int).SetUint64(p)})
+			}
+
+			return ListObject{factors}, nil
+		},
+	},
+	"det": {
+		Pointer: 103,
+		Arity:   arity_exact(1),
+		Doc:     "det(A) returns the determinant of the square matrix A",
+		Lists:   true,
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			m, err := square_matrix_arg("det", args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			return determinant(ctx, m)
+		},
+	},
+	"inv": {
+		Pointer: 104,
+		Arity:   arity_exact(1),
+		Doc:     "inv(A) returns the inverse of the square matrix A",
+		Lists:   true,
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			m, err := square_matrix_arg("inv", args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			result, err := invert_into(ctx, "inv", m, identity_matrix(m.rows()))
+			if err != nil {
+				return nil, err
+			}
+
+			return result.object(), nil
+		},
+	},
+	"transpose": {
+		Pointer: 105,
+		Arity:   arity_exact(1),
+		Doc:     "transpose(A) returns the matrix A with its rows and columns swapped",
+		Lists:   true,
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			m, err := matrix_arg("transpose", args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			result := new_matrix(m.cols(), m.rows(), nil)
+			for i, row := range m {
+				for j, value := range row {
+					result[j][i] = value
+				}
+			}
+
+			return result.object(), nil
+		},
+	},
+	"trace": {
+		Pointer: 106,
+		Arity:   arity_exact(1),
+		Doc:     "trace(A) returns the sum of the diagonal of the square matrix A",
+		Lists:   true,
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			m, err := square_matrix_arg("trace", args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			arith := &element_arith{ctx: ctx}
+			var result Object = big_int(0)
+			for i := range m {
+				result = arith.op(OpAdd, result, m[i][i])
+			}
+
+			return result, arith.err
+		},
+	},
+	"rank": {
+		Pointer: 107,
+		Arity:   arity_exact(1),
+		Doc:     "rank(A) returns the number of linearly independent rows of the matrix A",
+		Lists:   true,
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			m, err := matrix_arg("rank", args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			return matrix_rank(ctx, m)
+		},
+	},
+	"solve": {
+		Pointer: 108,
+		Arity:   arity_exact(2),
+		Doc:     "solve(A, b) returns x such that A * x = b for the square matrix A and the list or matrix b",
+		Lists:   true,
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			m, err := square_matrix_arg("solve", args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			return solve_system(ctx, "solve", m, args[1])
+		},
+	},
+	"eig": {
+		Pointer: 109,
+		Arity:   arity_exact(1),
+		Doc:     "eig(A) returns the eigenvalues of the real symmetric matrix A in ascending order",
+		Lists:   true,
+		ObjectFn: func(ctx *CallContext, args ...Object) (Object, error) {
+			m, err := square_matrix_arg("eig", args[0])
+			if err != nil {
+				return nil, err
+			}
+
+			return symmetric_eigenvalues(ctx, "eig", m)
 		},
 	},
 }
@@ -0,0 +1,310 @@
+package calc
+
+import (
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// builtin_case is an expression and its value from published tables, Tolerance
+// is relative to the expected value and absolute near zero. The default
+// tolerance is 1e-12 and an exact tolerance asks for the very value.
+type builtin_case struct {
+	Src       string
+	Expected  float64
+	Tolerance float64
+}
+
+const exact = -1
+
+func eval_float(t *testing.T, src string, opts ...Option) float64 {
+	t.Helper()
+
+	value, err := Eval(src, opts...)
+	if err != nil {
+		t.Fatalf("%s: %v", src, err)
+	}
+
+	number, err := to_float64(value)
+	if err != nil {
+		t.Fatalf("%s: %v", src, err)
+	}
+
+	return number.Value
+}
+
+func check_builtin_cases(t *testing.T, cases []builtin_case) {
+	t.Helper()
+
+	for _, test := range cases {
+		got := eval_float(t, test.Src)
+
+		if math.IsInf(test.Expected, 0) || math.IsNaN(test.Expected) {
+			if got != test.Expected && !(math.IsNaN(got) && math.IsNaN(test.Expected)) {
+				t.Errorf("%s = %v, want %v", test.Src, got, test.Expected)
+			}
+			continue
+		}
+
+		if test.Tolerance == exact {
+			if got != test.Expected {
+				t.Errorf("%s = %.17g, want exactly %.17g", test.Src, got, test.Expected)
+			}
+			continue
+		}
+
+		tolerance := test.Tolerance
+		if tolerance == 0 {
+			tolerance = 1e-12
+		}
+
+		if math.Abs(got-test.Expected) > tolerance*max(1, math.Abs(test.Expected)) {
+			t.Errorf("%s = %.17g, want %.17g", test.Src, got, test.Expected)
+		}
+	}
+}
+
+func TestSpecialFunctions(t *testing.T) {
+	check_builtin_cases(t, []builtin_case{
+		{Src: "gamma(5)", Expected: 24},
+		{Src: "gamma(10)", Expected: 362880},
+		{Src: "gamma(0.5)", Expected: 1.7724538509055160273},
+		{Src: "gamma(1.5)", Expected: 0.88622692545275801365},
+		{Src: "gamma(-0.5)", Expected: -3.5449077018110320546},
+		{Src: "gamma(0.1)", Expected: 9.5135076986687318397},
+		{Src: "lgamma(10)", Expected: 12.801827480081469611},
+		{Src: "lgamma(0.5)", Expected: 0.57236494292470008707},
+		{Src: "lgamma(100)", Expected: 359.13420536957539878},
+		{Src: "lgamma(-0.5)", Expected: 1.2655121234846453965},
+		{Src: "erf(0)", Expected: 0},
+		{Src: "erf(0.5)", Expected: 0.52049987781304653768},
+		{Src: "erf(1)", Expected: 0.84270079294971486934},
+		{Src: "erf(2)", Expected: 0.99532226501895273416},
+		{Src: "erf(-1)", Expected: -0.84270079294971486934},
+		{Src: "erfc(1)", Expected: 0.15729920705028513066},
+		{Src: "erfinv(0)", Expected: 0},
+		{Src: "erfinv(0.5)", Expected: 0.47693627620446987338},
+		{Src: "erfinv(0.9)", Expected: 1.1630871536766740867},
+		{Src: "erfinv(-0.5)", Expected: -0.47693627620446987338},
+		{Src: "erfinv(1)", Expected: math.Inf(1)},
+		{Src: "j0(0)", Expected: 1},
+		{Src: "j0(1)", Expected: 0.76519768655796655145},
+		{Src: "j0(5)", Expected: -0.17759677131433830435},
+		{Src: "j0(2.404825557695773)", Expected: 0, Tolerance: 1e-15},
+		{Src: "j1(1)", Expected: 0.44005058574493351596},
+		{Src: "jn(1, 1)", Expected: 0.44005058574493351596},
+		{Src: "jn(2, 1)", Expected: 0.11490348493190048047},
+		{Src: "jn(2, 5)", Expected: 0.046565116277752215532},
+		{Src: "jn(5, 10)", Expected: -0.23406152818679364044},
+		{Src: "y0(1)", Expected: 0.088256964215676957983},
+		{Src: "y0(5)", Expected: -0.30851762524903359787},
+		{Src: "y1(1)", Expected: -0.78121282130028871655},
+		{Src: "yn(1, 1)", Expected: -0.78121282130028871655},
+		{Src: "yn(2, 1)", Expected: -1.6506826068162543911},
+		{Src: "yn(2, 5)", Expected: 0.36766288260552432645},
+	})
+}
+
+func TestReciprocalTrigonometry(t *testing.T) {
+	check_builtin_cases(t, []builtin_case{
+		{Src: "sec(0)", Expected: 1},
+		{Src: "sec(pi / 3)", Expected: 2},
+		{Src: "sec(pi / 4)", Expected: math.Sqrt2},
+		{Src: "csc(pi / 6)", Expected: 2},
+		{Src: "csc(pi / 2)", Expected: 1},
+		{Src: "cot(pi / 4)", Expected: 1},
+		{Src: "cot(pi / 6)", Expected: math.Sqrt(3)},
+		{Src: "cot(0)", Expected: math.Inf(1)},
+		{Src: "asec(1)", Expected: 0},
+		{Src: "asec(2)", Expected: math.Pi / 3},
+		{Src: "asec(-2)", Expected: 2 * math.Pi / 3},
+		{Src: "acsc(1)", Expected: math.Pi / 2},
+		{Src: "acsc(2)", Expected: math.Pi / 6},
+		{Src: "acot(1)", Expected: math.Pi / 4},
+		{Src: "acot(sqrt(3))", Expected: math.Pi / 6},
+		{Src: "asec(sec(1.2))", Expected: 1.2},
+		{Src: "acsc(csc(0.7))", Expected: 0.7},
+		{Src: "acot(cot(0.3))", Expected: 0.3},
+	})
+}
+
+func TestDegreeTrigonometry(t *testing.T) {
+	half_sqrt2 := math.Sqrt2 / 2
+	half_sqrt3 := math.Sqrt(3) / 2
+
+	// the quadrant angles have to come out exact, not within a rounding error
+	check_builtin_cases(t, []builtin_case{
+		{Src: "sind(0)", Expected: 0, Tolerance: exact},
+		{Src: "sind(30)", Expected: 0.5, Tolerance: exact},
+		{Src: "sind(45)", Expected: half_sqrt2},
+		{Src: "sind(60)", Expected: half_sqrt3},
+		{Src: "sind(90)", Expected: 1, Tolerance: exact},
+		{Src: "sind(180)", Expected: 0, Tolerance: exact},
+		{Src: "sind(270)", Expected: -1, Tolerance: exact},
+		{Src: "cosd(0)", Expected: 1, Tolerance: exact},
+		{Src: "cosd(30)", Expected: half_sqrt3},
+		{Src: "cosd(45)", Expected: half_sqrt2},
+		{Src: "cosd(60)", Expected: 0.5, Tolerance: exact},
+		{Src: "cosd(90)", Expected: 0, Tolerance: exact},
+		{Src: "cosd(180)", Expected: -1, Tolerance: exact},
+		{Src: "cosd(270)", Expected: 0, Tolerance: exact},
+		{Src: "tand(0)", Expected: 0, Tolerance: exact},
+		{Src: "tand(30)", Expected: 1 / math.Sqrt(3)},
+		{Src: "tand(45)", Expected: 1, Tolerance: exact},
+		{Src: "tand(60)", Expected: math.Sqrt(3)},
+		{Src: "tand(90)", Expected: math.Inf(1)},
+		{Src: "tand(180)", Expected: 0, Tolerance: exact},
+		{Src: "tand(270)", Expected: math.Inf(-1)},
+	})
+}
+
+func TestExactStatistics(t *testing.T) {
+	rational := []Option{WithNumericMode(RationalMode)}
+	decimal := []Option{WithNumericMode(DecimalMode)}
+
+	// the differences to the exact values vanish only if no float got in
+	check_eval_cases(t, []eval_case{
+		{Src: "mean(1, 2, 2) - 5/3", Opts: rational, Expected: "0"},
+		{Src: "median(1/3, 1/2) - 5/12", Opts: rational, Expected: "0"},
+		{Src: "median(1/3, 1/7, 1/2) - 1/3", Opts: rational, Expected: "0"},
+		{Src: "percentile(25, 1/3, 1/2, 1) - 5/12", Opts: rational, Expected: "0"},
+		{Src: "variance(1, 2, 4) - 14/9", Opts: rational, Expected: "0"},
+		{Src: "min(1/3, 1/7) - 1/7", Opts: rational, Expected: "0"},
+		{Src: "max(1/3, 1/7) - 1/3", Opts: rational, Expected: "0"},
+		{Src: "clamp(1/3, 1/2, 1) - 1/2", Opts: rational, Expected: "0"},
+		{Src: "lerp(0, 1/3, 1/2) - 1/6", Opts: rational, Expected: "0"},
+		{Src: "mode(1/3, 1/7, 1/3) - 1/3", Opts: rational, Expected: "0"},
+		{Src: "percentile(101, 1/3)", Opts: rational, Err: "expects a percentile between 0 and 100"},
+		{Src: "svariance(1, 2, 4)", Opts: decimal, Expected: "2.33"},
+		{Src: "mean(1, 2, 2)", Opts: decimal, Expected: "1.67"},
+	})
+}
+
+// TestBigModePrecision checks the big.Float builtins against published digits
+// and identities that only hold to the last few of the 256 bits when nothing
+// goes through float64 on the way.
+func TestBigModePrecision(t *testing.T) {
+	big_mode := []Option{WithNumericMode(BigMode), WithPrecision(256)}
+
+	digits := []struct {
+		Src      string
+		Expected string
+	}{
+		{"sin(1)", "0.84147098480789650665250232163029899962256306079837106567275"},
+		{"cos(1)", "0.54030230586813971740093660744297660373231042061792222767009"},
+		{"4 atan(1)", "3.14159265358979323846264338327950288419716939937510582097494"},
+		{"cbrt(2)", "1.25992104989487316476721060727822835057025146470150798008197"},
+		{"log2(10)", "3.32192809488736234787031942948939017586483139302458061205475"},
+		{"log(2)", "0.69314718055994530941723212145817656807550013436025525412068"},
+	}
+
+	for _, test := range digits {
+		value, err := Eval(test.Src, big_mode...)
+		if err != nil {
+			t.Errorf("%s: %v", test.Src, err)
+			continue
+		}
+
+		if !strings.HasPrefix(value.String(), test.Expected) {
+			t.Errorf("%s = %s, want %s...", test.Src, value, test.Expected)
+		}
+	}
+
+	identities := []string{
+		"sin(0.7)^2 + cos(0.7)^2 - 1",
+		"sin(1e20)^2 + cos(1e20)^2 - 1",
+		"tan(0.7) * cot(0.7) - 1",
+		"sec(0.7) * cos(0.7) - 1",
+		"csc(0.7) * sin(0.7) - 1",
+		"asin(sin(0.5)) - 0.5",
+		"acos(cos(0.5)) - 0.5",
+		"acos(0.9999999999) - 2 asin(sqrt((1 - 0.9999999999) / 2))",
+		"atan(tan(-1.2)) + 1.2",
+		"atan2(-1, -1) + 3 atan(1)",
+		"asec(2) - acos(1 / 2)",
+		"acsc(2) - asin(1 / 2)",
+		"acot(3) - atan(1 / 3)",
+		"sinh(asinh(2)) - 2",
+		"cosh(acosh(2)) - 2",
+		"tanh(atanh(0.5)) - 0.5",
+		"sinh(1e-30) / 1e-30 - 1 - 1e-60 / 6",
+		"cbrt(2)^3 - 2",
+		"10^log10(7) - 7",
+		"2^log2(7) - 7",
+		"hypot(1, 1)^2 - 2",
+		"sind(30) - 0.5",
+		"tand(45) - 1",
+		"mean(1, 2, 2) - 5 / 3",
+	}
+
+	for _, src := range identities {
+		value, err := Eval(src, big_mode...)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+
+		x, ok := as_big_float(&CallContext{NumericMode: BigMode, Precision: 256}, value)
+		if !ok || x.Abs(x).Cmp(big.NewFloat(1e-70)) > 0 {
+			t.Errorf("%s = %s, want below 1e-70", src, value)
+		}
+	}
+
+	// in degrees the quadrant angles are exact
+	check_eval_cases(t, []eval_case{
+		{Src: "sin(180)", Opts: append([]Option{WithAngle(Degrees)}, big_mode...), Expected: "0"},
+		{Src: "cos(90)", Opts: append([]Option{WithAngle(Degrees)}, big_mode...), Expected: "0"},
+		{Src: "asin(1 / 2)", Opts: append([]Option{WithAngle(Degrees)}, big_mode...), Expected: "30"},
+		{Src: "sin(100)", Opts: append([]Option{WithAngle(Gradians)}, big_mode...), Expected: "1"},
+		{Src: "tand(90)", Opts: big_mode, Expected: "+Inf"},
+		{Src: "log(1)", Opts: big_mode, Expected: "0"},
+		{Src: "cbrt(-27)", Opts: big_mode, Expected: "-3"},
+		{Src: "hypot(3, 4)", Opts: big_mode, Expected: "5"},
+	})
+}
+
+// TestHugeRounding checks that rounding to far away places and printing huge
+// big floats finish rather than building powers of ten of the full size.
+func TestHugeRounding(t *testing.T) {
+	big_mode := []Option{WithNumericMode(BigMode)}
+	rational := []Option{WithNumericMode(RationalMode)}
+	decimal := []Option{WithNumericMode(DecimalMode)}
+
+	check_eval_cases(t, []eval_case{
+		{Src: "round(3, 100000000)", Opts: big_mode, Expected: "3"},
+		{Src: "round(1/3, 100000000) - 1/3", Opts: rational, Expected: "0"},
+		{Src: "round(1/3, 5) - 33333/100000", Opts: rational, Expected: "0"},
+		{Src: "round(1234.5678, -2)", Opts: rational, Expected: "1200"},
+		{Src: "round(5678, -4)", Opts: rational, Expected: "10000"},
+		{Src: "round(1234.5678, -100000000)", Opts: rational, Expected: "0"},
+		{Src: "round(-1/3, -9223372036854775808)", Opts: rational, Expected: "0"},
+		{Src: "round(1234.5678, -100000000)", Opts: decimal, Expected: "0"},
+		{Src: "round(5678, -4)", Opts: decimal, Expected: "10000"},
+		{Src: "2.5^100000000", Opts: big_mode, Expected: "7.36552589932140114942458322974146480151619196830518719126382515599692972579e+39794000"},
+		{Src: "0.5^100000000", Opts: big_mode, Expected: "2.71395023891769267447092067591621656267220499615076437970365502768704070717e-30103000"},
+	})
+
+	value, err := Eval("exp(1e9)", big_mode...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := NewFormatter().Format(value); !strings.HasPrefix(got, "8.002981770660972") || !strings.HasSuffix(got, "e+434294481") {
+		t.Errorf("exp(1e9) = %s", got)
+	}
+}
+
+func TestFloatRounding(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "round(1.23456, 2)", Expected: "1.23"},
+		{Src: "round(1234.5, -2)", Expected: "1200"},
+		{Src: "round(-2.5)", Expected: "-3"},
+		// places the scale overflows at or float64 cannot hold leave x as is
+		{Src: "round(1e300, 10)", Expected: "1e+300"},
+		{Src: "round(0.1, 400)", Expected: "0.1"},
+		{Src: "round(0.1, 20)", Expected: "0.1"},
+		{Src: "round(1e-20, 17)", Expected: "0"},
+		{Src: "round(1234, -400)", Expected: "0"},
+		{Src: "round(1/0, 2)", Expected: "+Inf"},
+	})
+}
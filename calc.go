@@ -0,0 +1,131 @@
+// Package calc evaluates calculator expressions like 2pi * 3 cm to in or
+// sqrt(16) + 10% of 50.
+//
+// Expressions go through a lexer, a parser and a compiler into a program for
+// a stack based vm. Eval does all of it at once, Compile stops at the program
+// so that it can be run more than once. Names that are not builtin constants
+// are the parameters of a program, each run binds them to a value:
+//
+//	program, err := calc.Compile("price * (1 + vat)")
+//	...
+//	total, err := program.RunWith(ctx, calc.Env{}, []float64{120, 0.2})
+//
+// A Program is never modified once compiled, any number of goroutines may run
+// it at the same time. Each run borrows a Machine, the stack and arguments of
+// a run, from a pool of the program. Vm and Machine offer the same split to
+// programs loaded from an archive: a Vm may be shared, a Machine belongs to one
+// goroutine at a time.
+package calc
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"sync"
+)
+
+// Value is the result of a program, one of the objects of the numeric mode
+// it was compiled for or a list or quantity of them. A Formatter turns it
+// into text. Values may share memory with the program that computed them and
+// must not be modified.
+type Value = Object
+
+// Env holds what a run of a program draws on besides the program itself.
+type Env struct {
+	// Rand is the random number generator of impure builtins like rand().
+	// When nil, runs are seeded with the seed given to Compile, or randomly
+	// without one. A rand.Rand is not safe for concurrent use, runs at the
+	// same time need one each.
+	Rand *rand.Rand
+	// Bindings are the values of the parameters of the program by name.
+	Bindings map[string]float64
+}
+
+// Program is a compiled expression. It is safe for concurrent use.
+type Program struct {
+	vm       *Vm
+	opts     []Option
+	impure   bool
+	machines sync.Pool
+}
+
+// Compile parses and compiles an expression with the options of the lexer,
+// parser, compiler and vm.
+func Compile(src string, opts ...Option) (*Program, error) {
+	parser := NewParser([]byte(src), "calc", opts...)
+	expr, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := NewCompiler(expr, opts...)
+	if _, err := compiler.Compile(); err != nil {
+		return nil, err
+	}
+
+	program := &Program{vm: NewVmFromCompiler(compiler, opts...), opts: opts}
+	program.impure = program.vm.impure()
+	program.machines.New = func() any {
+		return program.vm.NewMachine()
+	}
+
+	return program, nil
+}
+
+// Params are the names of the parameters of the program in the order they
+// first appear in.
+func (p *Program) Params() []string {
+	return slices.Clone(p.vm.Params)
+}
+
+// Run evaluates the program with the parameters bound by env, giving up once
+// ctx is done.
+func (p *Program) Run(ctx context.Context, env Env) (Value, error) {
+	machine := p.machine(env)
+	defer p.machines.Put(machine)
+
+	if err := machine.Bind(env.Bindings); err != nil {
+		return nil, err
+	}
+
+	return machine.Run(ctx)
+}
+
+// RunWith evaluates the program like Run with the values of the parameters
+// given in the order of Params instead of the bindings of env, which spares
+// looking them up by name.
+func (p *Program) RunWith(ctx context.Context, env Env, args []float64) (Value, error) {
+	machine := p.machine(env)
+	defer p.machines.Put(machine)
+
+	if err := machine.BindArgs(args); err != nil {
+		return nil, err
+	}
+
+	return machine.Run(ctx)
+}
+
+// machine takes a machine from the pool and seeds it for a run, only programs
+// that draw random numbers pay for a new generator.
+func (p *Program) machine(env Env) *Machine {
+	machine := p.machines.Get().(*Machine)
+
+	if p.impure {
+		machine.Rand = env.Rand
+		if machine.Rand == nil {
+			machine.Rand = new_rand(NewConfig(p.opts...).Seed)
+		}
+	}
+
+	return machine
+}
+
+// Eval compiles and runs an expression once.
+func Eval(src string, opts ...Option) (Value, error) {
+	program, err := Compile(src, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return program.Run(context.Background(), Env{})
+}
@@ -0,0 +1,210 @@
+package calc
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"testing"
+)
+
+// concurrent_programs cover every numeric mode, the args of a row i are
+// i, i+1, ... in the order of the parameters.
+var concurrent_programs = []struct {
+	src  string
+	opts []Option
+}{
+	{"price * (1 + vat) + 2x - x^2 + sqrt(x) + [1, 2, x][1] + (x * 1 m to cm) / 1 cm", nil},
+	{"x / 3 + 1/7 + x^2", []Option{WithNumericMode(RationalMode)}},
+	{"x * 1.5 + 10% of x + 2^100", []Option{WithNumericMode(BigMode)}},
+	{"x / 3 + 0.125", []Option{WithNumericMode(DecimalMode)}},
+	{"x * 3 + 0xff & 7", []Option{WithNumericMode(IntegerMode)}},
+	{"sin(x) + cos(y)", []Option{WithAngle(Degrees)}},
+	{"floor(rand() * 0) + x", nil},
+}
+
+const (
+	concurrent_rows       = 50
+	concurrent_goroutines = 16
+	concurrent_runs       = 200
+)
+
+func row_args(params []string, row int) []float64 {
+	args := make([]float64, len(params))
+	for i := range args {
+		args[i] = float64(row + i)
+	}
+
+	return args
+}
+
+func row_bindings(params []string, row int) map[string]float64 {
+	bindings := map[string]float64{}
+	for i, arg := range row_args(params, row) {
+		bindings[params[i]] = arg
+	}
+
+	return bindings
+}
+
+// expected_rows runs every row of a program one after another.
+func expected_rows(t *testing.T, program *Program) []string {
+	t.Helper()
+
+	expected := make([]string, concurrent_rows)
+	for row := range expected {
+		value, err := program.RunWith(context.Background(), Env{}, row_args(program.Params(), row))
+		if err != nil {
+			t.Fatalf("row %d: %v", row, err)
+		}
+		expected[row] = value.String()
+	}
+
+	return expected
+}
+
+// TestProgramConcurrentRuns runs one program from many goroutines at once and
+// checks that every run gets the result of running the same row alone. Run it
+// with -race.
+func TestProgramConcurrentRuns(t *testing.T) {
+	for _, test := range concurrent_programs {
+		t.Run(test.src, func(t *testing.T) {
+			program, err := Compile(test.src, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := expected_rows(t, program)
+			params := program.Params()
+
+			var wg sync.WaitGroup
+			for g := 0; g < concurrent_goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					env := Env{Rand: rand.New(rand.NewPCG(uint64(g), 0))}
+
+					for n := 0; n < concurrent_runs; n++ {
+						row := (n + g) % concurrent_rows
+
+						var value Value
+						var err error
+						if n%2 == 0 {
+							value, err = program.RunWith(context.Background(), env, row_args(params, row))
+						} else {
+							value, err = program.Run(context.Background(), Env{Bindings: row_bindings(params, row)})
+						}
+
+						if err != nil {
+							t.Errorf("row %d: %v", row, err)
+							return
+						}
+						if value.String() != expected[row] {
+							t.Errorf("row %d: got %s, want %s", row, value, expected[row])
+							return
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+// TestVmConcurrentMachines shares a vm loaded from an archive between
+// goroutines that each run it on a machine of their own.
+func TestVmConcurrentMachines(t *testing.T) {
+	for _, test := range concurrent_programs {
+		t.Run(test.src, func(t *testing.T) {
+			program, err := Compile(test.src, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := expected_rows(t, program)
+
+			parser := NewParser([]byte(test.src), "calc", test.opts...)
+			expr, err := parser.Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+			archive, err := NewCompiler(expr, test.opts...).Compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			vm, err := NewVm(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+			for g := 0; g < concurrent_goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					machine := vm.NewMachine()
+
+					for n := 0; n < concurrent_runs; n++ {
+						row := (n + g) % concurrent_rows
+						if err := machine.BindArgs(row_args(vm.Params, row)); err != nil {
+							t.Errorf("row %d: %v", row, err)
+							return
+						}
+
+						value, err := machine.Run(context.Background())
+						if err != nil {
+							t.Errorf("row %d: %v", row, err)
+							return
+						}
+						if value.String() != expected[row] {
+							t.Errorf("row %d: got %s, want %s", row, value, expected[row])
+							return
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+// TestImpureProgramConcurrentRuns checks that runs seeded alike draw the same
+// numbers while other runs draw from their own generators at the same time.
+func TestImpureProgramConcurrentRuns(t *testing.T) {
+	program, err := Compile("rand() + randint(1, 6) * x", WithSeed(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seeded, err := program.RunWith(context.Background(), Env{}, []float64{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < concurrent_goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			env := Env{Rand: rand.New(rand.NewPCG(uint64(g), 0))}
+
+			for n := 0; n < concurrent_runs; n++ {
+				if n%2 == 0 {
+					if _, err := program.RunWith(context.Background(), env, []float64{1}); err != nil {
+						t.Error(err)
+						return
+					}
+					continue
+				}
+
+				value, err := program.RunWith(context.Background(), Env{}, []float64{1})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if value.String() != seeded.String() {
+					t.Errorf("got %s, want %s with the seed of the program", value, seeded)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/CanPacis/calc"
+)
+
+// defaults holds the settings of the options no flag is given for.
+var defaults = calc.NewConfig()
+
+var (
+	seed      = flag.Uint64("seed", 0, "seed for the random number builtins, random when unset")
+	mode      = flag.String("mode", "float", "numeric mode, one of float, big, rational, decimal or integer")
+	precision = flag.Uint("precision", defaults.Precision, "mantissa precision in bits for the big numeric mode")
+	scale     = flag.Int("scale", int(defaults.Scale), "number of decimal places results are rounded to in the decimal mode")
+	rounding  = flag.String("rounding", "half-even", "rounding mode of the decimal mode, one of half-even, half-up or down")
+	word      = flag.String("word", defaults.Word.String(), "integer size of the integer mode, one of i8, i16, i32, i64, u8, u16, u32 or u64")
+	overflow  = flag.String("overflow", "wrap", "what integer overflows do in the integer mode, wrap or error")
+	angle     = flag.String("angle", "rad", "angle unit of the trigonometric builtins, one of rad, deg or grad")
+	implicit  = flag.String("implicit", "same", "precedence of implicit multiplication like 2pi, same as * or tight")
+	notation  = flag.String("notation", "auto", "how results are written, one of auto, fixed, sci or eng")
+	digits    = flag.Int("digits", -1, "decimal places of the fixed notation and significant digits otherwise, as many as needed when negative")
+	separator = flag.String("separator", "", "thousands separator of results, none when empty")
+	group     = flag.Bool("group", false, "group the thousands of results with the separator of the locale")
+	locale    = flag.String("locale", "", "locale of number literals and results like de_DE, taken from LC_ALL, LC_NUMERIC or LANG when unset")
+	base      = flag.String("base", "10", "base integral results are written in, one of 2, 8, 10 or 16")
+	output    = flag.String("output", "", "how to print rational results, one of fraction, mixed or decimal, fraction in the rational mode and decimal otherwise")
+)
+
+func main() {
+	flags, args := split_flags(os.Args[1:])
+	flag.CommandLine.Parse(flags)
+
+	numeric_mode, err := calc.ParseNumericMode(*mode)
+	check_err(err)
+
+	rounding_mode, err := calc.ParseRoundingMode(*rounding)
+	check_err(err)
+
+	integer_word, err := calc.ParseWord(*word)
+	check_err(err)
+
+	overflow_mode, err := calc.ParseOverflowMode(*overflow)
+	check_err(err)
+
+	angle_mode, err := calc.ParseAngleMode(*angle)
+	check_err(err)
+
+	implicit_precedence, err := calc.ParseImplicitPrecedence(*implicit)
+	check_err(err)
+
+	if *scale < 0 || *scale > math.MaxInt32 {
+		fmt.Printf("invalid scale of %d digits\n", *scale)
+		os.Exit(1)
+	}
+
+	style := calc.DecimalStyle
+	// asking for a notation or digits asks for decimals
+	if numeric_mode == calc.RationalMode && *notation == "auto" && *digits < 0 {
+		style = calc.FractionStyle
+	}
+	if *output != "" {
+		style, err = calc.ParseRationalStyle(*output)
+		check_err(err)
+	}
+
+	number_locale := calc.EnvironmentLocale()
+	if *locale != "" {
+		number_locale, err = calc.ParseLocale(*locale)
+		check_err(err)
+	}
+
+	formatter := calc.NewFormatter()
+	formatter.Locale = number_locale
+	formatter.Separator = *separator
+	if *group && *separator == "" {
+		formatter.Separator = string(number_locale.Group)
+	}
+	formatter.Rational = style
+	formatter.Digits = *digits
+	formatter.Notation, err = calc.ParseNotation(*notation)
+	check_err(err)
+	formatter.Base, err = calc.ParseBase(*base)
+	check_err(err)
+
+	opts := []calc.Option{
+		calc.WithNumericMode(numeric_mode),
+		calc.WithPrecision(*precision),
+		calc.WithScale(int32(*scale)),
+		calc.WithRounding(rounding_mode),
+		calc.WithWord(integer_word),
+		calc.WithOverflow(overflow_mode),
+		calc.WithImplicit(implicit_precedence),
+		calc.WithAngle(angle_mode),
+		calc.WithLocale(number_locale),
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			opts = append(opts, calc.WithSeed(*seed))
+		}
+	})
+
+	result, err := calc.Eval(strings.Join(args, " "), opts...)
+	check_err(err)
+
+	fmt.Println(formatter.Format(result))
+}
+
+func check_err(err error) {
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// split_flags separates the leading command line flags from the expression.
+// Only defined flags are taken, so an expression like -2 + 3 is not mistaken
+// for a flag.
+func split_flags(args []string) ([]string, []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return args[:i], args[i:]
+		}
+
+		name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		name, _, has_value := strings.Cut(name, "=")
+
+		if name == "h" || name == "help" {
+			continue
+		}
+
+		defined := flag.Lookup(name)
+		if defined == nil {
+			return args[:i], args[i:]
+		}
+
+		// flags other than booleans take their value from the next argument
+		bool_flag, ok := defined.Value.(interface{ IsBoolFlag() bool })
+		if !has_value && !(ok && bool_flag.IsBoolFlag()) {
+			i++
+		}
+	}
+
+	return args, []string{}
+}
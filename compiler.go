@@ -1,25 +1,81 @@
-package main
+package calc
 
-import "fmt"
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
 
 type Compiler struct {
 	ConstantPool ConstantPool
 	Expr         Expr
 	Instructions []Instruction
 	Version      uint32
+	NumericMode  NumericMode
+	Precision    uint
+	Scale        int32
+	Rounding     RoundingMode
+	Word         Word
+	Overflow     OverflowMode
+	Angle        AngleMode
+	// Params are the names the expression leaves free in the order they
+	// first appear in, OpLoad pushes the value bound to one of them.
+	Params []string
+	// Locations maps instructions to the source location of the operator or
+	// call they were compiled from. They are not part of the archive.
+	Locations map[int]Location
 }
 
 func (c *Compiler) Compile() ([]byte, error) {
-	err := c.compile_expr(c.Expr)
-	return c.serialize(), err
+	if c.NumericMode == BigMode && (c.Precision < 2 || c.Precision > big.MaxPrec) {
+		return nil, fmt.Errorf("invalid precision of %d bits", c.Precision)
+	}
+
+	if c.NumericMode == DecimalMode && c.Scale < 0 {
+		return nil, fmt.Errorf("invalid scale of %d digits", c.Scale)
+	}
+
+	if c.NumericMode == IntegerMode && !c.Word.valid() {
+		return nil, fmt.Errorf("invalid word size of %d bits", c.Word.Bits)
+	}
+
+	if _, _, err := c.check_units(c.Expr); err != nil {
+		return nil, err
+	}
+
+	if err := c.compile_expr(c.Expr); err != nil {
+		return nil, err
+	}
+
+	return c.serialize()
 }
 
-func (c Compiler) serialize() []byte {
+// serialize lays the archive out as the "calc.arc" magic, the version, the
+// numeric mode, precision, scale and rounding mode, the integer word and
+// overflow mode, the angle mode, the number of parameters followed by their
+// names prefixed by their size, the size of the constant pool followed by the
+// constants and finally every instruction prefixed by its size.
+func (c Compiler) serialize() ([]byte, error) {
 	result := []byte{}
 	result = append(result, []byte("calc.arc")...)
 	result = append(result, uint32_to_bytes(c.Version)...)
+	result = append(result, byte(c.NumericMode))
+	result = append(result, uint32_to_bytes(uint32(c.Precision))...)
+	result = append(result, uint32_to_bytes(uint32(c.Scale))...)
+	result = append(result, byte(c.Rounding))
+	result = append(result, c.Word.Bits, bool_to_byte(c.Word.Signed), byte(c.Overflow))
+	result = append(result, byte(c.Angle))
+
+	result = append(result, uint32_to_bytes(uint32(len(c.Params)))...)
+	for _, param := range c.Params {
+		result = append(result, uint32_to_bytes(uint32(len(param)))...)
+		result = append(result, param...)
+	}
 
-	constants := c.ConstantPool.Serialize()
+	constants, err := c.ConstantPool.Serialize()
+	if err != nil {
+		return nil, err
+	}
 	result = append(result, uint32_to_bytes(uint32(len(constants)))...)
 	result = append(result, constants...)
 
@@ -29,42 +85,236 @@ func (c Compiler) serialize() []byte {
 		result = append(result, serialized...)
 	}
 
-	return result
+	return result, nil
 }
 
 func (c *Compiler) compile_expr(e Expr) error {
 	switch expr := e.(type) {
 	case FloatLiteralExpr:
 		return c.compile_f_literal_expr(expr)
+	case ImaginaryLiteralExpr:
+		index := c.ConstantPool.Add(ComplexObject{complex(0, expr.Value)})
+		c.Instructions = append(c.Instructions, NewInstruction(OpConstant, index))
+		return nil
+	case QuantityExpr:
+		value, _, err := c.constant_object(expr)
+		if err != nil {
+			return err
+		}
+		index := c.ConstantPool.Add(value)
+		c.Instructions = append(c.Instructions, NewInstruction(OpConstant, index))
+		return nil
+	case ConvertExpr:
+		return c.compile_convert_expr(expr)
 	case ConstLiteralExpr:
 		return c.compile_c_literal_expr(expr)
 	case FnCallExpr:
 		return c.compile_call_expr(expr)
 	case BinaryExpr:
 		return c.compile_binary_expr(expr)
+	case UnaryExpr:
+		return c.compile_unary_expr(expr)
+	case AngleModeExpr:
+		c.Angle = expr.Mode
+		return c.compile_expr(expr.Expr)
+	case PercentExpr:
+		if err := c.compile_expr(expr.Expr); err != nil {
+			return err
+		}
+		c.Instructions = append(c.Instructions, NewInstruction(OpPercent))
+		return nil
+	case GroupExpr:
+		return c.compile_expr(expr.Expr)
+	case ListExpr:
+		return c.compile_list_expr(expr)
+	case IndexExpr:
+		return c.compile_index_expr(expr)
 	default:
 		return fmt.Errorf("unknown expression %s", expr.String())
 	}
 }
 
 func (c *Compiler) compile_f_literal_expr(expr FloatLiteralExpr) error {
-	index := c.ConstantPool.Add(Float64Object(expr))
+	value, err := c.literal_object(expr)
+	if err != nil {
+		return err
+	}
+
+	index := c.ConstantPool.Add(value)
 	c.Instructions = append(c.Instructions, NewInstruction(OpConstant, index))
 	return nil
 }
 
+// literal_object turns a number literal into the constant the numeric mode
+// calls for. IntegerMode fits literals into the word, DecimalMode and
+// RationalMode parse every literal exactly, BigMode keeps integer literals
+// exact and parses the others at the configured precision.
+func (c *Compiler) literal_object(expr FloatLiteralExpr) (Object, error) {
+	if c.NumericMode == FloatMode || expr.Literal == "" {
+		return Float64Object{expr.Value}, nil
+	}
+
+	if c.NumericMode == IntegerMode {
+		return int_literal(c.call_context(), expr.Literal)
+	}
+
+	if is_based_literal(expr.Literal) {
+		value, ok := new(big.Int).SetString(expr.Literal, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid number literal '%s'", expr.Literal)
+		}
+
+		return BigIntObject{value}, nil
+	}
+
+	if c.NumericMode == DecimalMode {
+		value, err := ParseDecimal(expr.Literal)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number literal '%s'", expr.Literal)
+		}
+
+		return value, nil
+	}
+
+	if c.NumericMode == RationalMode {
+		value, ok := new(big.Rat).SetString(expr.Literal)
+		if !ok {
+			return nil, fmt.Errorf("invalid number literal '%s'", expr.Literal)
+		}
+
+		return normalize_rat(value), nil
+	}
+
+	if !strings.ContainsAny(expr.Literal, ".eE") {
+		value, ok := new(big.Int).SetString(expr.Literal, 10)
+		if ok {
+			return BigIntObject{value}, nil
+		}
+	}
+
+	value, _, err := new_big_float(c.Precision).Parse(expr.Literal, 10)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number literal '%s'", expr.Literal)
+	}
+
+	return BigFloatObject{value}, nil
+}
+
+// compile_list_expr stores lists of literals as a single constant, any other
+// list is built at runtime from its items.
+func (c *Compiler) compile_list_expr(expr ListExpr) error {
+	if value, ok, err := c.constant_object(expr); ok || err != nil {
+		if err != nil {
+			return err
+		}
+
+		index := c.ConstantPool.Add(value)
+		c.Instructions = append(c.Instructions, NewInstruction(OpConstant, index))
+		return nil
+	}
+
+	for _, item := range expr.Items {
+		if err := c.compile_expr(item); err != nil {
+			return err
+		}
+	}
+
+	c.Instructions = append(c.Instructions, NewInstruction(OpList, len(expr.Items)))
+	return nil
+}
+
+// constant_object returns the value of number literals and of lists made of
+// them, it reports false for anything that has to be computed.
+func (c *Compiler) constant_object(e Expr) (Object, bool, error) {
+	switch expr := e.(type) {
+	case FloatLiteralExpr:
+		value, err := c.literal_object(expr)
+		return value, err == nil, err
+	case ImaginaryLiteralExpr:
+		return ComplexObject{complex(0, expr.Value)}, true, nil
+	case QuantityExpr:
+		value, err := c.literal_object(expr.Value)
+		if err != nil {
+			return nil, false, err
+		}
+		return QuantityObject{value, expr.Unit}, true, nil
+	case ListExpr:
+		values := make([]Object, len(expr.Items))
+		for i, item := range expr.Items {
+			value, ok, err := c.constant_object(item)
+			if !ok || err != nil {
+				return nil, ok, err
+			}
+			values[i] = value
+		}
+
+		return ListObject{values}, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// compile_convert_expr pushes the unit to convert to as a quantity of one
+// after the value.
+func (c *Compiler) compile_convert_expr(expr ConvertExpr) error {
+	if err := c.compile_expr(expr.Expr); err != nil {
+		return err
+	}
+
+	index := c.ConstantPool.Add(QuantityObject{big_int(1), expr.Unit})
+	c.Instructions = append(c.Instructions, NewInstruction(OpConstant, index), NewInstruction(OpConvert))
+	c.locate(expr.Location)
+	return nil
+}
+
+func (c *Compiler) compile_index_expr(expr IndexExpr) error {
+	if err := c.compile_expr(expr.Expr); err != nil {
+		return err
+	}
+
+	if err := c.compile_expr(expr.Index); err != nil {
+		return err
+	}
+
+	c.Instructions = append(c.Instructions, NewInstruction(OpIndex))
+	return nil
+}
+
+// compile_c_literal_expr pushes builtin constants, any other name becomes a
+// parameter of the program whose value is bound when it runs.
 func (c *Compiler) compile_c_literal_expr(expr ConstLiteralExpr) error {
 	builtin, ok := builtin_consts[expr.Name]
 
 	if !ok {
-		return fmt.Errorf("constant '%s' does not exist", expr.Name)
+		c.Instructions = append(c.Instructions, NewInstruction(OpLoad, c.param(expr.Name)))
+		return nil
+	}
+
+	var value Object = builtin
+	if c.NumericMode == BigMode {
+		if precise, ok := big_const(expr.Name, c.Precision); ok {
+			value = BigFloatObject{precise}
+		}
 	}
 
-	index := c.ConstantPool.Add(builtin)
+	index := c.ConstantPool.Add(value)
 	c.Instructions = append(c.Instructions, NewInstruction(OpConstant, index))
 	return nil
 }
 
+// param returns the index of a parameter, adding it the first time it is
+// used.
+func (c *Compiler) param(name string) int {
+	for i, param := range c.Params {
+		if param == name {
+			return i
+		}
+	}
+
+	c.Params = append(c.Params, name)
+	return len(c.Params) - 1
+}
+
 func (c *Compiler) compile_call_expr(expr FnCallExpr) error {
 	builtin, ok := builtin_fns[expr.Name]
 
@@ -72,6 +322,10 @@ func (c *Compiler) compile_call_expr(expr FnCallExpr) error {
 		return fmt.Errorf("function '%s' does not exist", expr.Name)
 	}
 
+	if err := builtin.Arity.Check(expr.Name, len(expr.Args)); err != nil {
+		return err
+	}
+
 	for _, arg := range expr.Args {
 		if err := c.compile_expr(arg); err != nil {
 			return err
@@ -79,42 +333,147 @@ func (c *Compiler) compile_call_expr(expr FnCallExpr) error {
 	}
 
 	c.Instructions = append(c.Instructions, NewInstruction(OpCall, builtin.Pointer, len(expr.Args)))
+	c.locate(expr.Location)
 
 	return nil
 }
 
+// binary_ops maps the binary operators of the syntax to their instructions.
+var binary_ops = map[OpType]Op{
+	OpTypeAdd:    OpAdd,
+	OpTypeSub:    OpSub,
+	OpTypeMul:    OpMul,
+	OpTypeDiv:    OpDiv,
+	OpTypeMod:    OpMod,
+	OpTypePow:    OpPow,
+	OpTypeIntDiv: OpIntDiv,
+	OpTypeAnd:    OpAnd,
+	OpTypeOr:     OpOr,
+	OpTypeXor:    OpXor,
+	OpTypeShl:    OpShl,
+	OpTypeShr:    OpShr,
+	// the percentage operations take the number in front of the % as is, so
+	// 50% of 80 is 50*80/100 and stays exact in IntegerMode
+	OpTypeAddPercent: OpAddPercent,
+	OpTypeSubPercent: OpSubPercent,
+	OpTypeOf:         OpPercentOf,
+	OpTypeOff:        OpSubPercent,
+}
+
+// binary_operands returns the operands of a binary expression in the order
+// its instruction takes them. Percentages give the number in front of their
+// %, and x% off y is y minus x% like a subtracted percentage.
+func binary_operands(expr BinaryExpr) (Expr, Expr) {
+	switch expr.Op {
+	case OpTypeAddPercent, OpTypeSubPercent:
+		return expr.Left, expr.Right.(PercentExpr).Expr
+	case OpTypeOf:
+		return expr.Left.(PercentExpr).Expr, expr.Right
+	case OpTypeOff:
+		return expr.Right, expr.Left.(PercentExpr).Expr
+	}
+
+	return expr.Left, expr.Right
+}
+
 func (c *Compiler) compile_binary_expr(expr BinaryExpr) error {
-	if err := c.compile_expr(expr.Left); err != nil {
+	if err := c.check_dimensions(expr); err != nil {
+		return err
+	}
+
+	left, right := binary_operands(expr)
+
+	if err := c.compile_expr(left); err != nil {
+		return err
+	}
+
+	if err := c.compile_expr(right); err != nil {
 		return err
 	}
 
-	if err := c.compile_expr(expr.Right); err != nil {
+	op, ok := binary_ops[expr.Op]
+	if !ok {
+		return fmt.Errorf("unknown binary operator %s", op_type_map[expr.Op])
+	}
+
+	c.Instructions = append(c.Instructions, NewInstruction(op))
+	c.locate(expr.Location)
+
+	return nil
+}
+
+// check_dimensions evaluates operations on constant lists ahead of time so
+// lists and matrices that do not fit together are reported before the
+// program runs. Any other error is left for the vm.
+func (c *Compiler) check_dimensions(expr BinaryExpr) error {
+	left_expr, right_expr := binary_operands(expr)
+
+	left, ok, _ := c.constant_object(left_expr)
+	if !ok {
+		return nil
+	}
+	right, ok, _ := c.constant_object(right_expr)
+	if !ok || !any_list([]Object{left, right}) {
+		return nil
+	}
+
+	_, err := binary_op(c.call_context(), binary_ops[expr.Op], left, right)
+	if dimension_err, ok := err.(DimensionError); ok {
+		dimension_err.Location = expr.Location
+		return dimension_err
+	}
+
+	return nil
+}
+
+// locate records the source location of the last instruction.
+func (c *Compiler) locate(location Location) {
+	c.Locations[len(c.Instructions)-1] = location
+}
+
+// call_context is the context of operations the compiler evaluates itself.
+func (c *Compiler) call_context() *CallContext {
+	return &CallContext{
+		NumericMode: c.NumericMode,
+		Precision:   c.Precision,
+		Scale:       c.Scale,
+		Rounding:    c.Rounding,
+		Word:        c.Word,
+		Overflow:    c.Overflow,
+		Angle:       c.Angle,
+	}
+}
+
+func (c *Compiler) compile_unary_expr(expr UnaryExpr) error {
+	if err := c.compile_expr(expr.Expr); err != nil {
 		return err
 	}
 
 	switch expr.Op {
-	case OpTypeAdd:
-		c.Instructions = append(c.Instructions, NewInstruction(OpAdd))
-	case OpTypeSub:
-		c.Instructions = append(c.Instructions, NewInstruction(OpSub))
-	case OpTypeMul:
-		c.Instructions = append(c.Instructions, NewInstruction(OpMul))
-	case OpTypeDiv:
-		c.Instructions = append(c.Instructions, NewInstruction(OpDiv))
-	case OpTypeMod:
-		c.Instructions = append(c.Instructions, NewInstruction(OpMod))
-	case OpTypePow:
-		c.Instructions = append(c.Instructions, NewInstruction(OpPow))
+	case OpTypeNot:
+		c.Instructions = append(c.Instructions, NewInstruction(OpNot))
+	default:
+		return fmt.Errorf("unknown unary operator %s", op_type_map[expr.Op])
 	}
 
 	return nil
 }
 
-func NewCompiler(expr Expr) *Compiler {
+func NewCompiler(expr Expr, opts ...Option) *Compiler {
+	config := NewConfig(opts...)
+
 	return &Compiler{
 		ConstantPool: NewConstantPool(),
 		Instructions: []Instruction{},
 		Expr:         expr,
-		Version:      1,
+		Version:      6,
+		NumericMode:  config.NumericMode,
+		Precision:    config.Precision,
+		Scale:        config.Scale,
+		Rounding:     config.Rounding,
+		Word:         config.Word,
+		Overflow:     config.Overflow,
+		Angle:        config.Angle,
+		Locations:    map[int]Location{},
 	}
 }
@@ -0,0 +1,159 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strconv"
+)
+
+// ComplexObject is a complex number. Complex values rank above every real
+// type, so arithmetic with a complex operand is carried out in complex128
+// whatever the numeric mode.
+type ComplexObject struct {
+	Value complex128
+}
+
+func (obj ComplexObject) GetValue() interface{} {
+	return obj.Value
+}
+
+// String prints complex numbers the way they are written, 3+4i, -2i or i.
+func (obj ComplexObject) String() string {
+	re, im := real(obj.Value), imag(obj.Value)
+
+	imaginary := strconv.FormatFloat(im, 'g', -1, 64) + "i"
+	switch im {
+	case 1:
+		imaginary = "i"
+	case -1:
+		imaginary = "-i"
+	}
+
+	if re == 0 {
+		return imaginary
+	}
+
+	// infinite parts already carry their sign
+	if imaginary[0] != '-' && imaginary[0] != '+' {
+		imaginary = "+" + imaginary
+	}
+
+	return strconv.FormatFloat(re, 'g', -1, 64) + imaginary
+}
+
+// ComplexFn is the optional complex counterpart of a BuiltinFn. It is called
+// for complex arguments, and for real arguments whose real result would be
+// NaN, which is how sqrt(-1) becomes i.
+type ComplexFn func(ctx *CallContext, args ...complex128) (complex128, error)
+
+func to_complex(obj Object) (complex128, error) {
+	if value, ok := obj.(ComplexObject); ok {
+		return value.Value, nil
+	}
+
+	value, err := to_float64(obj)
+	if err != nil {
+		return 0, err
+	}
+
+	return complex(value.Value, 0), nil
+}
+
+func any_complex(args []Object) bool {
+	for _, arg := range args {
+		if _, ok := arg.(ComplexObject); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// real_nan_result reports a NaN result from real arguments that are not NaN
+// themselves, the results the complex plane has an answer for.
+func real_nan_result(result Object, args []Object) bool {
+	value, ok := result.(Float64Object)
+	if !ok || !math.IsNaN(value.Value) {
+		return false
+	}
+
+	for _, arg := range args {
+		if value, ok := arg.(Float64Object); ok && math.IsNaN(value.Value) {
+			return false
+		}
+		if _, ok := arg.(ComplexObject); ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func complex_op(op Op, left, right complex128) (Object, error) {
+	switch op {
+	case OpAdd:
+		return ComplexObject{left + right}, nil
+	case OpSub:
+		return ComplexObject{left - right}, nil
+	case OpMul:
+		return ComplexObject{left * right}, nil
+	case OpDiv:
+		return ComplexObject{left / right}, nil
+	case OpPow:
+		return ComplexObject{complex_pow(left, right)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported operation %s for complex numbers", op)
+}
+
+// max_exact_complex_pow is the largest integer exponent complex_pow computes
+// by repeated squaring instead of through the polar form.
+const max_exact_complex_pow = 64
+
+// complex_pow avoids the rounding of the polar form for integer and half
+// exponents, so i^2 is exactly -1 and (-8)^0.5 has no real part.
+func complex_pow(base, exponent complex128) complex128 {
+	// cmplx.Pow panics on a zero base with an exponent like i*inf, whose real
+	// part is NaN and whose imaginary part is infinite
+	if base == 0 && math.IsNaN(real(exponent)) {
+		return cmplx.NaN()
+	}
+
+	if imag(exponent) != 0 {
+		return cmplx.Pow(base, exponent)
+	}
+
+	n := real(exponent)
+	if n == 0.5 {
+		return cmplx.Sqrt(base)
+	}
+
+	if n != math.Trunc(n) || math.Abs(n) > max_exact_complex_pow {
+		return cmplx.Pow(base, exponent)
+	}
+
+	result := complex(1, 0)
+	for k := int(math.Abs(n)); k > 0; k >>= 1 {
+		if k&1 == 1 {
+			result *= base
+		}
+		base *= base
+	}
+
+	if n < 0 {
+		return 1 / result
+	}
+
+	return result
+}
+
+// collapse_complex turns complex values without an imaginary part back into
+// real numbers.
+func collapse_complex(obj Object) Object {
+	if value, ok := obj.(ComplexObject); ok && imag(value.Value) == 0 {
+		return Float64Object{real(value.Value)}
+	}
+
+	return obj
+}
@@ -0,0 +1,52 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComplexString(t *testing.T) {
+	inf := math.Inf(1)
+	cases := []struct {
+		Value    complex128
+		Expected string
+	}{
+		{complex(3, 4), "3+4i"},
+		{complex(3, -4), "3-4i"},
+		{complex(0, -2), "-2i"},
+		{complex(0, 1), "i"},
+		{complex(1, -1), "1-i"},
+		{complex(inf, inf), "+Inf+Infi"},
+		{complex(inf, -inf), "+Inf-Infi"},
+		{complex(math.NaN(), inf), "NaN+Infi"},
+		{complex(2, math.NaN()), "2+NaNi"},
+	}
+
+	formatter := NewFormatter()
+	for _, test := range cases {
+		obj := ComplexObject{test.Value}
+
+		if got := obj.String(); got != test.Expected {
+			t.Errorf("String() of %v = %s, want %s", test.Value, got, test.Expected)
+		}
+		if got := formatter.Format(obj); got != test.Expected {
+			t.Errorf("Format() of %v = %s, want %s", test.Value, got, test.Expected)
+		}
+	}
+
+	check_eval_cases(t, []eval_case{
+		{Src: "(1 + 2i) / 0", Expected: "+Inf+Infi"},
+		{Src: "(1 - 2i) / 0", Expected: "+Inf-Infi"},
+	})
+}
+
+func TestComplexPowZeroBase(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "0^(i*inf)", Expected: "NaN+NaNi"},
+		{Src: "0^(3i/0)", Expected: "NaN+NaNi"},
+		{Src: "pow(0i, 3i/0)", Expected: "NaN+NaNi"},
+		{Src: "0i^(1/0)", Expected: "0"},
+		{Src: "0^(1+i)", Expected: "0"},
+		{Src: "0i^2", Expected: "0"},
+	})
+}
@@ -0,0 +1,188 @@
+package calc
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// NumericMode selects the representation the compiler uses for number
+// literals and the vm normalizes builtin results into.
+type NumericMode byte
+
+const (
+	// FloatMode evaluates everything with IEEE doubles.
+	FloatMode NumericMode = iota
+	// BigMode keeps integers exact with big.Int, divisions exact with big.Rat
+	// and everything else in big.Float with a configurable precision. The
+	// elementary builtins and the statistics compute to that precision, the
+	// special functions and distributions fall back to float64 and their
+	// results keep its 53 bits.
+	BigMode
+	// RationalMode keeps every literal and the results of + - * / exact with
+	// big.Rat, irrational builtins like sin or sqrt fall back to float64.
+	RationalMode
+	// DecimalMode computes with base 10 fixed-point decimals, rounding
+	// results to a configurable scale with a configurable rounding mode.
+	DecimalMode
+	// IntegerMode computes with fixed size integers of a configurable word,
+	// results that do not fit either wrap around or fail.
+	IntegerMode
+)
+
+var numeric_mode_map = map[NumericMode]string{
+	FloatMode:    "float",
+	BigMode:      "big",
+	RationalMode: "rational",
+	DecimalMode:  "decimal",
+	IntegerMode:  "integer",
+}
+
+func (mode NumericMode) String() string {
+	if name, ok := numeric_mode_map[mode]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("%d", mode)
+}
+
+func ParseNumericMode(name string) (NumericMode, error) {
+	for mode, mode_name := range numeric_mode_map {
+		if mode_name == name {
+			return mode, nil
+		}
+	}
+
+	return FloatMode, fmt.Errorf("unknown numeric mode '%s'", name)
+}
+
+const (
+	default_precision = 256
+	default_scale     = 2
+)
+
+// Config holds the settings shared by the compiler and the vm. It is built
+// from a list of options, anything left unset keeps its default.
+type Config struct {
+	Seed        uint64
+	NumericMode NumericMode
+	Precision   uint
+	Scale       int32
+	Rounding    RoundingMode
+	Word        Word
+	Overflow    OverflowMode
+	Implicit    ImplicitPrecedence
+	Angle       AngleMode
+	Locale      Locale
+	// Params are names declared as parameters, they are never read as units.
+	Params []string
+}
+
+type Option func(*Config)
+
+// WithSeed seeds the random number generator of the vm so that programs
+// calling impure builtins like rand() produce the same results on every run.
+func WithSeed(seed uint64) Option {
+	return func(c *Config) {
+		c.Seed = seed
+	}
+}
+
+// WithNumericMode selects the numeric mode the compiler builds the program
+// for, the mode is recorded in the archive so the vm does not need it.
+func WithNumericMode(mode NumericMode) Option {
+	return func(c *Config) {
+		c.NumericMode = mode
+	}
+}
+
+// WithPrecision sets the mantissa precision in bits of big.Float values in
+// BigMode.
+func WithPrecision(bits uint) Option {
+	return func(c *Config) {
+		c.Precision = bits
+	}
+}
+
+// WithScale sets the number of decimal places results are rounded to in
+// DecimalMode.
+func WithScale(scale int32) Option {
+	return func(c *Config) {
+		c.Scale = scale
+	}
+}
+
+// WithRounding sets the rounding mode of DecimalMode.
+func WithRounding(mode RoundingMode) Option {
+	return func(c *Config) {
+		c.Rounding = mode
+	}
+}
+
+// WithWord sets the size and signedness of the integers of IntegerMode.
+func WithWord(word Word) Option {
+	return func(c *Config) {
+		c.Word = word
+	}
+}
+
+// WithOverflow selects whether integers that overflow the word in
+// IntegerMode wrap around or fail.
+func WithOverflow(mode OverflowMode) Option {
+	return func(c *Config) {
+		c.Overflow = mode
+	}
+}
+
+// WithImplicit selects how tightly the parser binds implicit multiplication
+// like 2pi.
+func WithImplicit(precedence ImplicitPrecedence) Option {
+	return func(c *Config) {
+		c.Implicit = precedence
+	}
+}
+
+// WithAngle sets the angle mode of the trigonometric builtins, a mode
+// statement in the program takes precedence.
+func WithAngle(mode AngleMode) Option {
+	return func(c *Config) {
+		c.Angle = mode
+	}
+}
+
+// WithLocale sets the locale number literals and arguments are written in.
+func WithLocale(locale Locale) Option {
+	return func(c *Config) {
+		c.Locale = locale
+	}
+}
+
+// WithParams declares the names of the parameters a program is run with. A
+// declared name is never read as a unit, with the parameter t 2t is 2*t and
+// not two tonnes. Names used as operands elsewhere in the program are
+// parameters even without it.
+func WithParams(names ...string) Option {
+	return func(c *Config) {
+		c.Params = append(c.Params, names...)
+	}
+}
+
+func NewConfig(opts ...Option) Config {
+	config := Config{
+		Seed:        rand.Uint64(),
+		NumericMode: FloatMode,
+		Precision:   default_precision,
+		Scale:       default_scale,
+		Rounding:    HalfEven,
+		Word:        default_word,
+		Overflow:    Wrap,
+		Implicit:    ImplicitLoose,
+		Angle:       Radians,
+		Locale:      DefaultLocale,
+	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return config
+}
@@ -0,0 +1,263 @@
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RoundingMode selects how decimal results with more digits than the
+// configured scale are rounded.
+type RoundingMode byte
+
+const (
+	// HalfEven rounds ties to the nearest even digit, also known as banker's
+	// rounding.
+	HalfEven RoundingMode = iota
+	// HalfUp rounds ties away from zero.
+	HalfUp
+	// Down truncates towards zero.
+	Down
+)
+
+var rounding_mode_map = map[RoundingMode]string{
+	HalfEven: "half-even",
+	HalfUp:   "half-up",
+	Down:     "down",
+}
+
+func (mode RoundingMode) String() string {
+	if name, ok := rounding_mode_map[mode]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("%d", mode)
+}
+
+func ParseRoundingMode(name string) (RoundingMode, error) {
+	for mode, mode_name := range rounding_mode_map {
+		if mode_name == name {
+			return mode, nil
+		}
+	}
+
+	return HalfEven, fmt.Errorf("unknown rounding mode '%s'", name)
+}
+
+// DecimalObject is a base 10 fixed-point number, its value is
+// Unscaled * 10^-Scale. The scale is never negative.
+type DecimalObject struct {
+	Unscaled *big.Int
+	Scale    int32
+}
+
+func (obj DecimalObject) GetValue() interface{} {
+	return obj.Rat()
+}
+
+func (obj DecimalObject) String() string {
+	digits := new(big.Int).Abs(obj.Unscaled).String()
+	sign := ""
+	if obj.Unscaled.Sign() < 0 {
+		sign = "-"
+	}
+
+	if obj.Scale <= 0 {
+		return sign + digits + strings.Repeat("0", int(-obj.Scale))
+	}
+
+	scale := int(obj.Scale)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+func (obj DecimalObject) Rat() *big.Rat {
+	r := new(big.Rat).SetInt(obj.Unscaled)
+	return r.Quo(r, new(big.Rat).SetInt(pow10(int64(obj.Scale))))
+}
+
+// rescale returns the unscaled value of obj at a larger scale.
+func (obj DecimalObject) rescale(scale int32) *big.Int {
+	return new(big.Int).Mul(obj.Unscaled, pow10(int64(scale-obj.Scale)))
+}
+
+func pow10(n int64) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(abs_int64(n)), nil)
+}
+
+var errInvalidDecimal = errors.New("invalid decimal literal")
+
+// ParseDecimal reads a decimal literal exactly, digits[.digits] with an
+// optional sign and exponent, keeping the number of fractional digits as the
+// scale.
+func ParseDecimal(literal string) (DecimalObject, error) {
+	mantissa, exponent := literal, int64(0)
+
+	if i := strings.IndexAny(literal, "eE"); i >= 0 {
+		mantissa = literal[:i]
+		value, err := strconv.ParseInt(literal[i+1:], 10, 32)
+		if err != nil {
+			return DecimalObject{}, errInvalidDecimal
+		}
+		exponent = value
+	}
+
+	whole, fraction, _ := strings.Cut(mantissa, ".")
+	unscaled, ok := new(big.Int).SetString(whole+fraction, 10)
+	if !ok {
+		return DecimalObject{}, errInvalidDecimal
+	}
+
+	scale := int64(len(fraction)) - exponent
+	if scale < 0 {
+		unscaled.Mul(unscaled, pow10(-scale))
+		scale = 0
+	}
+	if scale > math.MaxInt32 {
+		return DecimalObject{}, errInvalidDecimal
+	}
+
+	return DecimalObject{Unscaled: unscaled, Scale: int32(scale)}, nil
+}
+
+// round_rat rounds r to the given number of decimal places and returns the
+// unscaled result.
+func round_rat(r *big.Rat, scale int32, mode RoundingMode) *big.Int {
+	factor := new(big.Rat).SetInt(pow10(int64(scale)))
+	scaled := new(big.Rat)
+	if scale >= 0 {
+		scaled.Mul(r, factor)
+	} else {
+		scaled.Quo(r, factor)
+	}
+
+	quotient, remainder := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if remainder.Sign() == 0 || mode == Down {
+		return quotient
+	}
+
+	// compare twice the remainder with the denominator to find ties
+	twice := new(big.Int).Abs(remainder)
+	twice.Lsh(twice, 1)
+	step := big.NewInt(int64(scaled.Sign()))
+
+	switch cmp := twice.Cmp(scaled.Denom()); {
+	case cmp > 0:
+		quotient.Add(quotient, step)
+	case cmp == 0 && (mode == HalfUp || quotient.Bit(0) == 1):
+		quotient.Add(quotient, step)
+	}
+
+	return quotient
+}
+
+func decimal_from_rat(r *big.Rat, scale int32, mode RoundingMode) DecimalObject {
+	// terminating rationals keep their own scale if it fits
+	if digits, exact := r.FloatPrec(); exact && digits <= int(scale) {
+		scale = int32(digits)
+	}
+
+	return DecimalObject{Unscaled: round_rat(r, scale, mode), Scale: scale}
+}
+
+// decimal_from_float goes through the shortest decimal representation of the
+// float, so 0.1 becomes 0.1 and not the binary approximation.
+func decimal_from_float(value float64, scale int32, mode RoundingMode) (DecimalObject, bool) {
+	if !is_finite(value) {
+		return DecimalObject{}, false
+	}
+
+	parsed, err := ParseDecimal(strconv.FormatFloat(value, 'g', -1, 64))
+	if err != nil {
+		return DecimalObject{}, false
+	}
+
+	if parsed.Scale <= scale {
+		return parsed, true
+	}
+
+	return DecimalObject{Unscaled: round_rat(parsed.Rat(), scale, mode), Scale: scale}, true
+}
+
+func to_decimal(ctx *CallContext, obj Object) DecimalObject {
+	switch value := obj.(type) {
+	case DecimalObject:
+		return value
+	case IntObject:
+		return DecimalObject{Unscaled: value.Big(), Scale: 0}
+	case BigIntObject:
+		return DecimalObject{Unscaled: value.Value, Scale: 0}
+	case BigRatObject:
+		return decimal_from_rat(value.Value, ctx.Scale, ctx.Rounding)
+	}
+
+	return DecimalObject{Unscaled: new(big.Int)}
+}
+
+// limit_scale rounds results whose scale exceeds the configured one down to
+// it, like products whose scale is the sum of the operand scales or sums of
+// literals written with more places than the scale.
+func limit_scale(ctx *CallContext, value DecimalObject) DecimalObject {
+	if value.Scale <= ctx.Scale {
+		return value
+	}
+
+	return DecimalObject{Unscaled: round_rat(value.Rat(), ctx.Scale, ctx.Rounding), Scale: ctx.Scale}
+}
+
+func decimal_op(ctx *CallContext, op Op, left, right DecimalObject) (Object, error) {
+	scale := max(left.Scale, right.Scale)
+
+	switch op {
+	case OpAdd:
+		return limit_scale(ctx, DecimalObject{new(big.Int).Add(left.rescale(scale), right.rescale(scale)), scale}), nil
+	case OpSub:
+		return limit_scale(ctx, DecimalObject{new(big.Int).Sub(left.rescale(scale), right.rescale(scale)), scale}), nil
+	case OpMul:
+		return limit_scale(ctx, DecimalObject{new(big.Int).Mul(left.Unscaled, right.Unscaled), left.Scale + right.Scale}), nil
+	case OpDiv:
+		if right.Unscaled.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		quotient := new(big.Rat).Quo(left.Rat(), right.Rat())
+		return DecimalObject{round_rat(quotient, ctx.Scale, ctx.Rounding), ctx.Scale}, nil
+	case OpMod:
+		if right.Unscaled.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		// the remainder of the rescaled integers is exact, truncated like math.Mod
+		return limit_scale(ctx, DecimalObject{new(big.Int).Rem(left.rescale(scale), right.rescale(scale)), scale}), nil
+	case OpPow:
+		// binary_op brings inexact powers back to decimals
+		return big_rat_op(ctx, op, left.Rat(), right.Rat())
+	}
+
+	return nil, fmt.Errorf("unsupported operation %s", op)
+}
+
+// decimal_round rounds to the given number of decimal places with the
+// configured rounding mode, used by round() in DecimalMode.
+func decimal_round(ctx *CallContext, value DecimalObject, digits int32) DecimalObject {
+	if value.Scale <= digits {
+		return value
+	}
+
+	// the value is below 2^bits, so it rounds to zero at any place from 10^bits on
+	if digits < 0 && int64(digits) <= int64(value.Scale)-int64(value.Unscaled.BitLen()) {
+		return DecimalObject{Unscaled: new(big.Int)}
+	}
+
+	unscaled := round_rat(value.Rat(), digits, ctx.Rounding)
+	if digits < 0 {
+		// rounding to tens or hundreds, scale the result back to an integer
+		return DecimalObject{Unscaled: unscaled.Mul(unscaled, pow10(int64(digits))), Scale: 0}
+	}
+
+	return DecimalObject{Unscaled: unscaled, Scale: digits}
+}
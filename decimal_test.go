@@ -0,0 +1,25 @@
+package calc
+
+import "testing"
+
+func TestDecimalScale(t *testing.T) {
+	decimal := []Option{WithNumericMode(DecimalMode)}
+	half_up := []Option{WithNumericMode(DecimalMode), WithRounding(HalfUp)}
+
+	check_eval_cases(t, []eval_case{
+		{Src: "1.005", Opts: decimal, Expected: "1.00"},
+		{Src: "1.015", Opts: decimal, Expected: "1.02"},
+		{Src: "1.005", Opts: half_up, Expected: "1.01"},
+		{Src: "0.125 + 0.125", Opts: decimal, Expected: "0.25"},
+		{Src: "1.005 + 0", Opts: decimal, Expected: "1.00"},
+		{Src: "1.005 * 1", Opts: decimal, Expected: "1.00"},
+		{Src: "1.255 - 0.25", Opts: decimal, Expected: "1.00"},
+		{Src: "7.125 % 2", Opts: decimal, Expected: "1.12"},
+		{Src: "[1.005, 2]", Opts: decimal, Expected: "[1.00, 2]"},
+		{Src: "0.1 + 0.2", Opts: decimal, Expected: "0.3"},
+		// the operands keep their places, only the result is rounded
+		{Src: "1.005 * 1000", Opts: decimal, Expected: "1005.00"},
+		{Src: "1.004 + 0.004", Opts: decimal, Expected: "1.01"},
+		{Src: "1.0049", Opts: []Option{WithNumericMode(DecimalMode), WithScale(3)}, Expected: "1.005"},
+	})
+}
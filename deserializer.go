@@ -1,4 +1,4 @@
-package main
+package calc
 
 import (
 	"errors"
@@ -6,6 +6,14 @@ import (
 
 type Deserialized struct {
 	Version      uint32
+	NumericMode  NumericMode
+	Precision    uint
+	Scale        int32
+	Rounding     RoundingMode
+	Word         Word
+	Overflow     OverflowMode
+	Angle        AngleMode
+	Params       []string
 	ConstantPool ConstantPool
 	Instructions []Instruction
 }
@@ -23,12 +31,76 @@ func (d Deserializer) Deserialize() (*Deserialized, error) {
 	}
 
 	deserialized.Version = d.deserialize_version()
+	deserialized.NumericMode = FloatMode
+	deserialized.Precision = default_precision
+	deserialized.Scale = default_scale
+	deserialized.Rounding = HalfEven
+	deserialized.Word = default_word
+	deserialized.Overflow = Wrap
+	deserialized.Angle = Radians
 
-	pool, err := d.deserialize_constant_pool()
-	if err != nil {
-		return nil, err
+	// version 1 archives predate the numeric modes and only hold floats
+	if deserialized.Version == 1 {
+		pool, err := d.deserialize_float_constant_pool()
+		if err != nil {
+			return nil, err
+		}
+		deserialized.ConstantPool = pool
+	} else {
+		if !d.has(5) {
+			return nil, errors.New("broken archive")
+		}
+		deserialized.NumericMode = NumericMode(d.slice(1)[0])
+		d.offset += 1
+		deserialized.Precision = uint(bytes_to_uint32(d.slice(4)))
+		d.offset += 4
+
+		// version 3 added the decimal mode settings
+		if deserialized.Version >= 3 {
+			if !d.has(5) {
+				return nil, errors.New("broken archive")
+			}
+			deserialized.Scale = int32(bytes_to_uint32(d.slice(4)))
+			d.offset += 4
+			deserialized.Rounding = RoundingMode(d.slice(1)[0])
+			d.offset += 1
+		}
+
+		// version 4 added the integer mode settings
+		if deserialized.Version >= 4 {
+			if !d.has(3) {
+				return nil, errors.New("broken archive")
+			}
+			settings := d.slice(3)
+			deserialized.Word = Word{Bits: settings[0], Signed: settings[1] == 1}
+			deserialized.Overflow = OverflowMode(settings[2])
+			d.offset += 3
+		}
+
+		// version 5 added the angle mode
+		if deserialized.Version >= 5 {
+			if !d.has(1) {
+				return nil, errors.New("broken archive")
+			}
+			deserialized.Angle = AngleMode(d.slice(1)[0])
+			d.offset += 1
+		}
+
+		// version 6 added the parameters
+		if deserialized.Version >= 6 {
+			params, err := d.deserialize_params()
+			if err != nil {
+				return nil, err
+			}
+			deserialized.Params = params
+		}
+
+		pool, err := d.deserialize_constant_pool()
+		if err != nil {
+			return nil, err
+		}
+		deserialized.ConstantPool = pool
 	}
-	deserialized.ConstantPool = pool
 
 	instructions, err := d.deserialize_instructions()
 	if err != nil {
@@ -43,6 +115,10 @@ func (d *Deserializer) slice(n int) []byte {
 	return d.input[d.offset : d.offset+n]
 }
 
+func (d *Deserializer) has(n int) bool {
+	return d.offset+n <= len(d.input)
+}
+
 func (d *Deserializer) validate_archive() bool {
 	validator_str := "calc.arc"
 	archive := d.slice(len(validator_str))
@@ -60,7 +136,68 @@ func (d *Deserializer) deserialize_version() uint32 {
 	return version
 }
 
+func (d *Deserializer) deserialize_params() ([]string, error) {
+	if !d.has(4) {
+		return nil, errors.New("broken archive")
+	}
+	count := int(bytes_to_uint32(d.slice(4)))
+	d.offset += 4
+
+	params := []string{}
+	for i := 0; i < count; i++ {
+		if !d.has(4) {
+			return nil, errors.New("broken archive")
+		}
+		size := int(bytes_to_uint32(d.slice(4)))
+		d.offset += 4
+
+		if !d.has(size) {
+			return nil, errors.New("broken archive")
+		}
+		params = append(params, string(d.slice(size)))
+		d.offset += size
+	}
+
+	return params, nil
+}
+
 func (d *Deserializer) deserialize_constant_pool() (ConstantPool, error) {
+	pool := ConstantPool{}
+	if !d.has(4) {
+		return pool, errors.New("broken archive")
+	}
+	size := int(bytes_to_uint32(d.slice(4)))
+	d.offset += 4
+
+	if !d.has(size) {
+		return pool, errors.New("broken archive")
+	}
+	end := d.offset + size
+
+	for d.offset < end {
+		if d.offset+5 > end {
+			return pool, errors.New("broken archive")
+		}
+		tag := object_tag(d.slice(1)[0])
+		d.offset += 1
+		length := int(bytes_to_uint32(d.slice(4)))
+		d.offset += 4
+
+		if d.offset+length > end {
+			return pool, errors.New("broken archive")
+		}
+		value, err := deserialize_object(tag, d.slice(length))
+		if err != nil {
+			return pool, err
+		}
+		pool.Add(value)
+		d.offset += length
+	}
+
+	return pool, nil
+}
+
+func (d *Deserializer) deserialize_float_constant_pool() (ConstantPool, error) {
 	pool := ConstantPool{}
 	size := bytes_to_uint32(d.slice(4))
 	d.offset += 4
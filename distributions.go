@@ -0,0 +1,294 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	special_max_iterations = 500
+	special_epsilon        = 1e-15
+	special_tiny           = 1e-300
+
+	// below this many terms summing the probability mass directly is both
+	// cheap and more accurate than the incomplete beta and gamma functions
+	direct_sum_limit = 1000
+
+	// above this many degrees of freedom the t distribution is the standard
+	// normal one to about 1e-8, while the incomplete beta function loses its
+	// precision to the huge arguments of the gamma functions
+	student_t_normal_df = 1e7
+)
+
+// regularized_beta computes the regularized incomplete beta function I_x(a, b)
+// with the continued fraction expansion, evaluated through the modified Lentz
+// method. The symmetry I_x(a, b) = 1 - I_1-x(b, a) keeps the fraction in the
+// range where it converges quickly.
+func regularized_beta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	if x > (a+1)/(a+b+2) {
+		return 1 - regularized_beta(1-x, b, a)
+	}
+
+	ln_front := lgamma(a+b) - lgamma(a) - lgamma(b) + a*math.Log(x) + b*math.Log1p(-x)
+	front := math.Exp(ln_front) / a
+
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < special_tiny {
+		d = special_tiny
+	}
+	d = 1 / d
+	result := d
+
+	for m := 1; m <= special_max_iterations; m++ {
+		m := float64(m)
+
+		// even step of the fraction
+		numerator := m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		d = 1 + numerator*d
+		if math.Abs(d) < special_tiny {
+			d = special_tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < special_tiny {
+			c = special_tiny
+		}
+		d = 1 / d
+		result *= d * c
+
+		// odd step of the fraction
+		numerator = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		d = 1 + numerator*d
+		if math.Abs(d) < special_tiny {
+			d = special_tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < special_tiny {
+			c = special_tiny
+		}
+		d = 1 / d
+		delta := d * c
+		result *= delta
+
+		if math.Abs(delta-1) < special_epsilon {
+			break
+		}
+	}
+
+	return front * result
+}
+
+// regularized_gamma_q computes the regularized upper incomplete gamma function
+// Q(a, x), using the power series of P(a, x) below a+1 and a continued fraction
+// above it.
+func regularized_gamma_q(a, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+
+	ln_front := a*math.Log(x) - x - lgamma(a)
+
+	if x < a+1 {
+		term := 1 / a
+		sum := term
+		for n := 1; n <= special_max_iterations; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*special_epsilon {
+				break
+			}
+		}
+
+		return 1 - sum*math.Exp(ln_front)
+	}
+
+	b := x + 1 - a
+	c := 1 / special_tiny
+	d := 1 / b
+	result := d
+
+	for n := 1; n <= special_max_iterations; n++ {
+		an := -float64(n) * (float64(n) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < special_tiny {
+			d = special_tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < special_tiny {
+			c = special_tiny
+		}
+		d = 1 / d
+		delta := d * c
+		result *= delta
+
+		if math.Abs(delta-1) < special_epsilon {
+			break
+		}
+	}
+
+	return math.Exp(ln_front) * result
+}
+
+func normal_params(name string, args []Float64Object) (mu, sigma float64, err error) {
+	mu, sigma = 0, 1
+
+	if len(args) > 1 {
+		mu = args[1].Value
+	}
+	if len(args) > 2 {
+		sigma = args[2].Value
+	}
+
+	if !(sigma > 0) {
+		return 0, 0, DomainError{Name: name, Reason: fmt.Sprintf("expects a positive standard deviation but got %g", sigma)}
+	}
+
+	return mu, sigma, nil
+}
+
+func probability_arg(name string, arg Float64Object) (float64, error) {
+	p := arg.Value
+
+	if !(p >= 0 && p <= 1) {
+		return 0, DomainError{Name: name, Reason: fmt.Sprintf("expects a probability between 0 and 1 but got %g", p)}
+	}
+
+	return p, nil
+}
+
+func rate_arg(name string, arg Float64Object) (float64, error) {
+	rate := arg.Value
+
+	if !(rate > 0) || math.IsInf(rate, 1) {
+		return 0, DomainError{Name: name, Reason: fmt.Sprintf("expects a positive rate but got %g", rate)}
+	}
+
+	return rate, nil
+}
+
+func binomial_params(name string, args []Float64Object) (k, n int64, p float64, err error) {
+	k, err = int_arg(name, args[0])
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	n, err = natural_arg(name, args[1])
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	p, err = probability_arg(name, args[2])
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	return k, n, p, nil
+}
+
+func binomial_pdf(k, n int64, p float64) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+
+	// the log form below is undefined at the boundaries of p
+	if p == 0 || p == 1 {
+		if (p == 0 && k == 0) || (p == 1 && k == n) {
+			return 1
+		}
+		return 0
+	}
+
+	kf, nf := float64(k), float64(n)
+
+	// the exact coefficient is more accurate than lgamma for as long as it
+	// fits into a float64
+	if choose := binomial_coefficient(n, k); !math.IsInf(choose, 1) {
+		return choose * math.Pow(p, kf) * math.Pow(1-p, nf-kf)
+	}
+
+	ln_choose := lgamma(nf+1) - lgamma(kf+1) - lgamma(nf-kf+1)
+	return math.Exp(ln_choose + kf*math.Log(p) + (nf-kf)*math.Log1p(-p))
+}
+
+func binomial_cdf(k, n int64, p float64) float64 {
+	switch {
+	case k < 0:
+		return 0
+	case k >= n:
+		return 1
+	case p == 0:
+		return 1
+	case p == 1:
+		return 0
+	}
+
+	if n <= direct_sum_limit {
+		sum := 0.0
+		for i := int64(0); i <= k; i++ {
+			sum += binomial_pdf(i, n, p)
+		}
+		return math.Min(sum, 1)
+	}
+
+	return regularized_beta(1-p, float64(n-k), float64(k+1))
+}
+
+func poisson_pdf(k int64, lambda float64) float64 {
+	if k < 0 {
+		return 0
+	}
+
+	kf := float64(k)
+	return math.Exp(kf*math.Log(lambda) - lambda - lgamma(kf+1))
+}
+
+func poisson_cdf(k int64, lambda float64) float64 {
+	if k < 0 {
+		return 0
+	}
+
+	// e^-lambda underflows for large rates, leave those to the gamma function
+	if k <= direct_sum_limit && lambda < 700 {
+		term := math.Exp(-lambda)
+		sum := term
+		for i := int64(1); i <= k; i++ {
+			term *= lambda / float64(i)
+			sum += term
+		}
+		return math.Min(sum, 1)
+	}
+
+	return regularized_gamma_q(float64(k+1), lambda)
+}
+
+// student_t_cdf uses the relation between the t distribution and the
+// incomplete beta function, P(T <= t) = 1 - I_x(df/2, 1/2)/2 with x = df/(df+t²)
+// for positive t and the symmetric value for negative t. Large df fall back to
+// the normal distribution.
+func student_t_cdf(t, df float64) float64 {
+	if math.IsInf(t, 1) {
+		return 1
+	}
+	if math.IsInf(t, -1) {
+		return 0
+	}
+
+	if df > student_t_normal_df {
+		return math.Erfc(-t/math.Sqrt2) / 2
+	}
+
+	tail := 0.5 * math.Min(math.Max(regularized_beta(df/(df+t*t), df/2, 0.5), 0), 1)
+
+	if t > 0 {
+		return 1 - tail
+	}
+
+	return tail
+}
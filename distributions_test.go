@@ -0,0 +1,89 @@
+package calc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalDistribution(t *testing.T) {
+	check_builtin_cases(t, []builtin_case{
+		{Src: "normpdf(0)", Expected: 0.39894228040143267794},
+		{Src: "normpdf(1)", Expected: 0.24197072451914336500},
+		{Src: "normpdf(2, 1, 2)", Expected: 0.17603266338214976},
+		{Src: "normcdf(0)", Expected: 0.5},
+		{Src: "normcdf(1.96)", Expected: 0.97500210485177952},
+		{Src: "normcdf(-1)", Expected: 0.15865525393145705},
+		{Src: "normcdf(130, 100, 15)", Expected: 0.97724986805182079},
+		{Src: "norminv(0.5)", Expected: 0},
+		{Src: "norminv(0.975)", Expected: 1.9599639845400542, Tolerance: 1e-9},
+		{Src: "norminv(0.05)", Expected: -1.6448536269514729, Tolerance: 1e-9},
+		{Src: "norminv(0.9, 100, 15)", Expected: 119.22327348316901, Tolerance: 1e-9},
+		{Src: "normcdf(norminv(0.3))", Expected: 0.3, Tolerance: 1e-9},
+	})
+}
+
+func TestDiscreteDistributions(t *testing.T) {
+	check_builtin_cases(t, []builtin_case{
+		{Src: "binompdf(3, 10, 0.5)", Expected: 0.1171875},
+		{Src: "binomcdf(3, 10, 0.5)", Expected: 0.171875},
+		{Src: "binompdf(0, 5, 0.2)", Expected: 0.32768},
+		{Src: "binomcdf(2, 5, 0.2)", Expected: 0.94208},
+		{Src: "binomcdf(10, 10, 0.3)", Expected: 1},
+		{Src: "poissonpdf(0, 1)", Expected: 0.36787944117144233},
+		{Src: "poissonpdf(2, 3)", Expected: 0.22404180765538775},
+		{Src: "poissoncdf(2, 3)", Expected: 0.42319008112684353},
+		{Src: "poissoncdf(5, 2)", Expected: 0.98343639151938556},
+		{Src: "poissoncdf(-1, 2)", Expected: 0},
+	})
+}
+
+func TestContinuousDistributions(t *testing.T) {
+	check_builtin_cases(t, []builtin_case{
+		{Src: "exppdf(1, 2)", Expected: 0.27067056647322538},
+		{Src: "expcdf(1, 2)", Expected: 0.86466471676338730},
+		{Src: "expcdf(0, 2)", Expected: 0},
+		// df 1 and 2 have closed forms, the others come from t tables
+		{Src: "tcdf(0, 5)", Expected: 0.5, Tolerance: 1e-9},
+		{Src: "tcdf(1, 1)", Expected: 0.75, Tolerance: 1e-9},
+		{Src: "tcdf(1, 2)", Expected: 0.78867513459481287, Tolerance: 1e-9},
+		{Src: "tcdf(1.8124611228116756, 10)", Expected: 0.95, Tolerance: 1e-9},
+		{Src: "tcdf(2.2281388519649385, 10)", Expected: 0.975, Tolerance: 1e-9},
+		{Src: "tcdf(-2.5705818366147395, 5)", Expected: 0.025, Tolerance: 1e-9},
+		{Src: "tcdf(2.7874358136769706, 25)", Expected: 0.995, Tolerance: 1e-9},
+		// large df approach the normal distribution, normcdf(1) and normcdf(-3)
+		{Src: "tcdf(1, 1e6)", Expected: 0.84134474606854293, Tolerance: 1e-6},
+		{Src: "tcdf(1, 1e7)", Expected: 0.84134474606854293, Tolerance: 1e-7},
+		{Src: "tcdf(1, 1e8)", Expected: 0.84134474606854293, Tolerance: 1e-8},
+		{Src: "tcdf(1, 1e14)", Expected: 0.84134474606854293, Tolerance: 1e-9},
+		{Src: "tcdf(1, 1e15)", Expected: 0.84134474606854293, Tolerance: 1e-9},
+		{Src: "tcdf(-3, 1e300)", Expected: 0.0013498980316300946, Tolerance: 1e-9},
+		{Src: "tcdf(40, 1e6)", Expected: 1},
+	})
+}
+
+func TestDistributionErrors(t *testing.T) {
+	cases := []struct {
+		Src string
+		Err string
+	}{
+		{"norminv(1.5)", "expects a probability between 0 and 1 but got 1.5"},
+		{"norminv(-0.1)", "expects a probability between 0 and 1 but got -0.1"},
+		{"binompdf(1, 3, 1.2)", "expects a probability between 0 and 1 but got 1.2"},
+		{"binomcdf(1, 3, -0.1)", "expects a probability between 0 and 1 but got -0.1"},
+		{"normpdf(0, 0, 0)", "expects a positive standard deviation but got 0"},
+		{"normcdf(0, 0, -1)", "expects a positive standard deviation but got -1"},
+		{"norminv(0.5, 0, -2)", "expects a positive standard deviation but got -2"},
+		{"tcdf(1, 0)", "expects positive degrees of freedom but got 0"},
+		{"tcdf(1, -2)", "expects positive degrees of freedom but got -2"},
+		{"poissonpdf(1, -1)", "expects a positive rate but got -1"},
+		{"exppdf(1, 0)", "expects a positive rate but got 0"},
+		{"binompdf(1.5, 3, 0.5)", "expects integer arguments but got 1.5"},
+	}
+
+	for _, test := range cases {
+		_, err := Eval(test.Src)
+		if err == nil || !strings.Contains(err.Error(), test.Err) {
+			t.Errorf("%s: got error %v, want one containing %q", test.Src, err, test.Err)
+		}
+	}
+}
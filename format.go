@@ -0,0 +1,427 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RationalStyle selects how exact rational results are printed.
+type RationalStyle byte
+
+const (
+	// FractionStyle prints improper fractions like 3/2.
+	FractionStyle RationalStyle = iota
+	// MixedStyle prints mixed numbers like 1 1/2.
+	MixedStyle
+	// DecimalStyle prints decimals, exact when the expansion terminates.
+	DecimalStyle
+)
+
+var rational_style_map = map[RationalStyle]string{
+	FractionStyle: "fraction",
+	MixedStyle:    "mixed",
+	DecimalStyle:  "decimal",
+}
+
+func (style RationalStyle) String() string {
+	if name, ok := rational_style_map[style]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("%d", style)
+}
+
+func ParseRationalStyle(name string) (RationalStyle, error) {
+	for style, style_name := range rational_style_map {
+		if style_name == name {
+			return style, nil
+		}
+	}
+
+	return DecimalStyle, fmt.Errorf("unknown rational output style '%s'", name)
+}
+
+func FormatRational(r *big.Rat, style RationalStyle) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+
+	switch style {
+	case FractionStyle:
+		return r.RatString()
+	case MixedStyle:
+		whole := rat_trunc(r)
+		if whole.Sign() == 0 {
+			return r.RatString()
+		}
+
+		remainder := new(big.Rat).Sub(r, new(big.Rat).SetInt(whole))
+		return fmt.Sprintf("%s %s", whole, remainder.Abs(remainder).RatString())
+	default:
+		return BigRatObject{r}.String()
+	}
+}
+
+// FormatObject prints a result, rationals follow the given style and every
+// other value prints as itself.
+func FormatObject(obj Object, style RationalStyle) string {
+	formatter := NewFormatter()
+	formatter.Rational = style
+	return formatter.Format(obj)
+}
+
+// Notation selects how the formatter writes real numbers.
+type Notation byte
+
+const (
+	// AutoNotation writes numbers plainly and switches to an exponent for
+	// very large and very small magnitudes.
+	AutoNotation Notation = iota
+	// FixedNotation writes a fixed number of decimal places.
+	FixedNotation
+	// ScientificNotation writes one digit before the point and an exponent.
+	ScientificNotation
+	// EngineeringNotation writes one to three digits before the point and an
+	// exponent that is a multiple of three.
+	EngineeringNotation
+)
+
+var notation_map = map[Notation]string{
+	AutoNotation:        "auto",
+	FixedNotation:       "fixed",
+	ScientificNotation:  "sci",
+	EngineeringNotation: "eng",
+}
+
+func (notation Notation) String() string {
+	if name, ok := notation_map[notation]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("%d", notation)
+}
+
+func ParseNotation(name string) (Notation, error) {
+	for notation, notation_name := range notation_map {
+		if notation_name == name {
+			return notation, nil
+		}
+	}
+
+	return AutoNotation, fmt.Errorf("unknown notation '%s'", name)
+}
+
+// auto_exponent bounds the magnitudes AutoNotation writes without an
+// exponent, from 1e-7 up to but not including 1e21.
+const (
+	auto_min_exponent = -7
+	auto_max_exponent = 21
+)
+
+// Formatter turns results into text. The zero value is not ready to use,
+// NewFormatter returns one that prints every value like its String method
+// except that floats only use an exponent for extreme magnitudes.
+type Formatter struct {
+	Notation Notation
+	// Digits are the decimal places of FixedNotation and the significant
+	// digits of the other notations. A negative value prints as many digits
+	// as it takes to tell the value apart.
+	Digits int
+	// Separator groups the digits before the point by thousands, no grouping
+	// is done when it is empty.
+	Separator string
+	// Locale sets the decimal separator and the separator of list items.
+	Locale Locale
+	// Base is 2, 8, 10 or 16, integral results are written in it with a 0b,
+	// 0o or 0x prefix. Other results are always decimal.
+	Base int
+	// Rational is how exact rationals that are not integers are written,
+	// only DecimalStyle is affected by the notation.
+	Rational RationalStyle
+}
+
+func NewFormatter() Formatter {
+	return Formatter{
+		Notation: AutoNotation,
+		Digits:   -1,
+		Base:     10,
+		Rational: DecimalStyle,
+		Locale:   DefaultLocale,
+	}
+}
+
+func (f Formatter) Format(obj Object) string {
+	switch value := obj.(type) {
+	case ListObject:
+		return value.format(f.Format, string(f.Locale.Separator)+" ")
+	case QuantityObject:
+		return fmt.Sprintf("%s %s", f.Format(value.Value), value.Unit)
+	case ComplexObject:
+		return f.format_complex(value.Value)
+	case Float64Object:
+		return f.format_float64(value.Value)
+	case BigIntObject:
+		return f.format_int(value.Value)
+	case IntObject:
+		return f.format_int(value.Big())
+	case BigFloatObject:
+		if integer, ok := integral_float(value.Value); ok && f.Base != 10 {
+			return f.format_int(integer)
+		}
+		return f.format_big_float(value.Value)
+	case BigRatObject:
+		return f.format_rat(value.Value, value.String)
+	case DecimalObject:
+		return f.format_rat(value.Rat(), value.String)
+	}
+
+	return obj.String()
+}
+
+func (f Formatter) format_float64(x float64) string {
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+
+	if f.Base != 10 && x == math.Trunc(x) {
+		integer, _ := new(big.Float).SetFloat64(x).Int(nil)
+		return f.format_int(integer)
+	}
+
+	switch f.Notation {
+	case FixedNotation:
+		if f.Digits >= 0 {
+			return f.localize(strconv.FormatFloat(x, 'f', f.Digits, 64))
+		}
+	case AutoNotation:
+		if f.Digits < 0 {
+			exponent := 0
+			if x != 0 {
+				exponent = int(math.Floor(math.Log10(math.Abs(x))))
+			}
+			if exponent < auto_min_exponent || exponent >= auto_max_exponent {
+				return f.localize(strconv.FormatFloat(x, 'e', -1, 64))
+			}
+			return f.localize(strconv.FormatFloat(x, 'f', -1, 64))
+		}
+	}
+
+	return f.format_exponent(strconv.FormatFloat(x, 'e', f.significant()-1, 64))
+}
+
+// significant returns the significant digits of the formatter, zero when it
+// prints the shortest representation.
+func (f Formatter) significant() int {
+	return max(f.Digits, 0)
+}
+
+func (f Formatter) format_big_float(x *big.Float) string {
+	if x.IsInf() {
+		return x.String()
+	}
+
+	switch f.Notation {
+	case FixedNotation:
+		if f.Digits >= 0 {
+			return f.localize(x.Text('f', f.Digits))
+		}
+	case AutoNotation:
+		if f.Digits < 0 {
+			return f.localize(BigFloatObject{x}.String())
+		}
+	}
+
+	digits := f.significant()
+	if digits == 0 {
+		digits = display_digits(x.Prec())
+	}
+	return f.format_exponent(big_float_text(x, 'e', digits-1))
+}
+
+// format_rat formats exact values, display is how they print themselves in
+// AutoNotation when no digits are asked for.
+func (f Formatter) format_rat(r *big.Rat, display func() string) string {
+	if r.IsInt() && f.Base != 10 {
+		return f.format_int(r.Num())
+	}
+
+	if !r.IsInt() && f.Rational != DecimalStyle {
+		return FormatRational(r, f.Rational)
+	}
+
+	switch f.Notation {
+	case FixedNotation:
+		if f.Digits >= 0 {
+			return f.localize(r.FloatString(f.Digits))
+		}
+	case AutoNotation:
+		if f.Digits < 0 {
+			return f.localize(display())
+		}
+	}
+
+	return f.format_big_float(new(big.Float).SetPrec(rat_display_prec).SetRat(r))
+}
+
+func (f Formatter) format_int(x *big.Int) string {
+	if prefix, ok := base_prefixes[f.Base]; ok {
+		sign := ""
+		if x.Sign() < 0 {
+			sign = "-"
+		}
+		return sign + prefix + new(big.Int).Abs(x).Text(f.Base)
+	}
+
+	switch f.Notation {
+	case FixedNotation:
+		if f.Digits >= 0 {
+			return f.localize(new(big.Rat).SetInt(x).FloatString(f.Digits))
+		}
+	case AutoNotation:
+		if f.Digits < 0 {
+			return f.localize(x.String())
+		}
+	}
+
+	prec := max(uint(x.BitLen()), 64)
+	return f.format_big_float(new(big.Float).SetPrec(prec).SetInt(x))
+}
+
+// format_complex writes complex numbers the way ComplexObject does, with both
+// parts formatted in decimal.
+func (f Formatter) format_complex(z complex128) string {
+	re, im := real(z), imag(z)
+	f.Base = 10
+
+	imaginary := f.format_float64(im) + "i"
+	switch im {
+	case 1:
+		imaginary = "i"
+	case -1:
+		imaginary = "-i"
+	}
+
+	if re == 0 {
+		return imaginary
+	}
+
+	// infinite parts already carry their sign
+	if imaginary[0] != '-' && imaginary[0] != '+' {
+		imaginary = "+" + imaginary
+	}
+
+	return f.format_float64(re) + imaginary
+}
+
+var base_prefixes = map[int]string{
+	2:  "0b",
+	8:  "0o",
+	16: "0x",
+}
+
+// ParseBase parses the base results are written in, one of 2, 8, 10 or 16.
+func ParseBase(name string) (int, error) {
+	base, err := strconv.Atoi(name)
+	if _, ok := base_prefixes[base]; err != nil || (!ok && base != 10) {
+		return 10, fmt.Errorf("invalid base '%s'", name)
+	}
+
+	return base, nil
+}
+
+// format_exponent rewrites a number printed with an exponent, like -1.2345e+06,
+// into the notation of the formatter.
+func (f Formatter) format_exponent(text string) string {
+	mantissa, exponent_text, _ := strings.Cut(text, "e")
+	exponent, _ := strconv.Atoi(exponent_text)
+
+	sign := ""
+	if strings.HasPrefix(mantissa, "-") {
+		sign = "-"
+		mantissa = mantissa[1:]
+	}
+	digits := strings.Replace(mantissa, ".", "", 1)
+	if f.significant() == 0 {
+		// exact values are printed with more digits than they have
+		digits = strings.TrimRight(digits, "0")
+		if digits == "" {
+			digits = "0"
+		}
+	}
+
+	switch f.Notation {
+	case ScientificNotation:
+		return f.localize(sign + point_digits(digits, 1) + format_exponent_suffix(exponent))
+	case EngineeringNotation:
+		shift := ((exponent % 3) + 3) % 3
+		return f.localize(sign + point_digits(digits, 1+shift) + format_exponent_suffix(exponent-shift))
+	}
+
+	if exponent < auto_min_exponent || exponent >= auto_max_exponent {
+		return f.localize(sign + point_digits(strings.TrimRight(digits, "0"), 1) + format_exponent_suffix(exponent))
+	}
+
+	if exponent < 0 {
+		digits = strings.Repeat("0", -exponent) + digits
+		exponent = 0
+	}
+	plain := point_digits(digits, exponent+1)
+	if strings.Contains(plain, ".") {
+		plain = strings.TrimSuffix(strings.TrimRight(plain, "0"), ".")
+	}
+	return f.localize(sign + plain)
+}
+
+// point_digits puts a decimal point after the first n digits, padding them
+// with zeros when there are fewer.
+func point_digits(digits string, n int) string {
+	if len(digits) <= n {
+		return digits + strings.Repeat("0", n-len(digits))
+	}
+
+	return digits[:n] + "." + digits[n:]
+}
+
+func format_exponent_suffix(exponent int) string {
+	return fmt.Sprintf("e%+03d", exponent)
+}
+
+// localize writes a number with the decimal separator of the locale and puts
+// the separator between the thousands of a plainly written number.
+func (f Formatter) localize(text string) string {
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign = "-"
+		text = text[1:]
+	}
+
+	integer, fraction, has_point := strings.Cut(text, ".")
+	if f.Separator != "" && !strings.ContainsAny(integer, "e/ ") {
+		grouped := []string{}
+		for len(integer) > 3 {
+			grouped = append([]string{integer[len(integer)-3:]}, grouped...)
+			integer = integer[:len(integer)-3]
+		}
+		grouped = append([]string{integer}, grouped...)
+		integer = strings.Join(grouped, f.Separator)
+	}
+
+	result := sign + integer
+	if has_point {
+		result += string(f.Locale.Decimal) + fraction
+	}
+	return result
+}
+
+// integral_float returns the integer value of a float without a fraction.
+func integral_float(x *big.Float) (*big.Int, bool) {
+	if x.IsInf() || !x.IsInt() {
+		return nil, false
+	}
+
+	integer, _ := x.Int(nil)
+	return integer, true
+}
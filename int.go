@@ -0,0 +1,425 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Word describes the fixed size integers of IntegerMode, their width in bits
+// and whether they are signed.
+type Word struct {
+	Bits   uint8
+	Signed bool
+}
+
+var default_word = Word{Bits: 64, Signed: true}
+
+func (w Word) String() string {
+	if w.Signed {
+		return fmt.Sprintf("i%d", w.Bits)
+	}
+
+	return fmt.Sprintf("u%d", w.Bits)
+}
+
+// ParseWord reads word names like i32 or u8.
+func ParseWord(name string) (Word, error) {
+	if len(name) > 1 && (name[0] == 'i' || name[0] == 'u') {
+		bits, _ := strconv.Atoi(name[1:])
+		word := Word{Bits: uint8(bits), Signed: name[0] == 'i'}
+		if word.valid() && bits == int(word.Bits) {
+			return word, nil
+		}
+	}
+
+	return default_word, fmt.Errorf("unknown word size '%s', expecting one of i8, i16, i32, i64, u8, u16, u32 or u64", name)
+}
+
+func (w Word) valid() bool {
+	switch w.Bits {
+	case 8, 16, 32, 64:
+		return true
+	}
+
+	return false
+}
+
+func (w Word) min() *big.Int {
+	if !w.Signed {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), uint(w.Bits-1)))
+}
+
+func (w Word) max() *big.Int {
+	bits := uint(w.Bits)
+	if w.Signed {
+		bits--
+	}
+
+	limit := new(big.Int).Lsh(big.NewInt(1), bits)
+	return limit.Sub(limit, big.NewInt(1))
+}
+
+func (w Word) fits(value *big.Int) bool {
+	return value.Cmp(w.min()) >= 0 && value.Cmp(w.max()) <= 0
+}
+
+// wrap keeps the low bits of value the way fixed size integers overflow,
+// negative values are taken as their two's complement.
+func (w Word) wrap(value *big.Int) IntObject {
+	modulus := new(big.Int).Lsh(big.NewInt(1), uint(w.Bits))
+	mask := new(big.Int).Sub(modulus, big.NewInt(1))
+	result := new(big.Int).And(value, mask)
+
+	if w.Signed && result.Bit(int(w.Bits)-1) == 1 {
+		result.Sub(result, modulus)
+	}
+
+	return int_object(w, result)
+}
+
+// OverflowMode selects what happens to results that do not fit the word.
+type OverflowMode byte
+
+const (
+	// Wrap keeps the low bits of the result like fixed size integers do.
+	Wrap OverflowMode = iota
+	// Trap fails the evaluation with an OverflowError.
+	Trap
+)
+
+var overflow_mode_map = map[OverflowMode]string{
+	Wrap: "wrap",
+	Trap: "error",
+}
+
+func (mode OverflowMode) String() string {
+	if name, ok := overflow_mode_map[mode]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("%d", mode)
+}
+
+func ParseOverflowMode(name string) (OverflowMode, error) {
+	for mode, mode_name := range overflow_mode_map {
+		if mode_name == name {
+			return mode, nil
+		}
+	}
+
+	return Wrap, fmt.Errorf("unknown overflow mode '%s'", name)
+}
+
+// OverflowError is returned in IntegerMode when a result does not fit the
+// configured word and overflows are not allowed to wrap.
+type OverflowError struct {
+	Value *big.Int
+	Word  Word
+}
+
+func (err OverflowError) Error() string {
+	return fmt.Sprintf("integer overflow, %s does not fit into %s", err.Value, err.Word)
+}
+
+// IntObject is a fixed size integer of IntegerMode. Value holds the two's
+// complement bits of the integer, unsigned words read them as an uint64.
+type IntObject struct {
+	Value    int64
+	Unsigned bool
+}
+
+func (obj IntObject) GetValue() interface{} {
+	if obj.Unsigned {
+		return uint64(obj.Value)
+	}
+
+	return obj.Value
+}
+
+func (obj IntObject) String() string {
+	if obj.Unsigned {
+		return strconv.FormatUint(uint64(obj.Value), 10)
+	}
+
+	return strconv.FormatInt(obj.Value, 10)
+}
+
+func (obj IntObject) Big() *big.Int {
+	if obj.Unsigned {
+		return new(big.Int).SetUint64(uint64(obj.Value))
+	}
+
+	return big.NewInt(obj.Value)
+}
+
+// int_object expects a value that already fits the word.
+func int_object(w Word, value *big.Int) IntObject {
+	if w.Signed {
+		return IntObject{Value: value.Int64()}
+	}
+
+	return IntObject{Value: int64(value.Uint64()), Unsigned: true}
+}
+
+// fit_int brings an integer into the word, wrapping or failing according to
+// the overflow mode.
+func fit_int(ctx *CallContext, value *big.Int) (IntObject, error) {
+	if ctx.Word.fits(value) {
+		return int_object(ctx.Word, value), nil
+	}
+
+	if ctx.Overflow == Trap {
+		return IntObject{}, OverflowError{Value: value, Word: ctx.Word}
+	}
+
+	return ctx.Word.wrap(value), nil
+}
+
+// to_int truncates any finite numeric object to an integer of the word, used
+// to bring builtin results computed with float64 back into IntegerMode.
+func to_int(ctx *CallContext, obj Object) (Object, error) {
+	switch value := obj.(type) {
+	case IntObject:
+		return value, nil
+	case Float64Object:
+		if !is_finite(value.Value) {
+			return nil, fmt.Errorf("%s has no integer value", value)
+		}
+	}
+
+	r, ok := exact_rat(obj)
+	if !ok {
+		return nil, fmt.Errorf("%s has no integer value", obj)
+	}
+
+	return fit_int(ctx, rat_trunc(r))
+}
+
+// is_based_literal reports number literals written with a 0x, 0b or 0o
+// prefix.
+func is_based_literal(literal string) bool {
+	literal = strings.TrimPrefix(literal, "-")
+	if len(literal) < 2 || literal[0] != '0' {
+		return false
+	}
+
+	switch literal[1] {
+	case 'x', 'X', 'b', 'B', 'o', 'O':
+		return true
+	}
+
+	return false
+}
+
+// int_literal parses an integer literal of IntegerMode. Based literals are
+// bit patterns, so 0xff is -1 for an i8 word.
+func int_literal(ctx *CallContext, literal string) (Object, error) {
+	value := new(big.Int)
+
+	if is_based_literal(literal) {
+		if _, ok := value.SetString(literal, 0); !ok {
+			return nil, fmt.Errorf("invalid number literal '%s'", literal)
+		}
+
+		if value.Sign() > 0 && !ctx.Word.fits(value) && value.BitLen() <= int(ctx.Word.Bits) {
+			return ctx.Word.wrap(value), nil
+		}
+	} else {
+		r, ok := new(big.Rat).SetString(literal)
+		if !ok {
+			return nil, fmt.Errorf("invalid number literal '%s'", literal)
+		}
+		if !r.IsInt() {
+			return nil, fmt.Errorf("'%s' is not an integer literal", literal)
+		}
+		value = r.Num()
+	}
+
+	return fit_int(ctx, value)
+}
+
+// int_op implements the arithmetic operators of IntegerMode. Results are
+// returned as big integers and fitted into the word by normalize.
+func int_op(ctx *CallContext, op Op, left, right IntObject) (Object, error) {
+	l, r := left.Big(), right.Big()
+
+	switch op {
+	case OpAdd:
+		return BigIntObject{l.Add(l, r)}, nil
+	case OpSub:
+		return BigIntObject{l.Sub(l, r)}, nil
+	case OpMul:
+		return BigIntObject{l.Mul(l, r)}, nil
+	case OpDiv:
+		if r.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		// Quo truncates towards zero like integer division in Go
+		return BigIntObject{l.Quo(l, r)}, nil
+	case OpMod:
+		if r.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return BigIntObject{l.Rem(l, r)}, nil
+	case OpPow:
+		return int_pow(ctx, l, r)
+	}
+
+	return nil, fmt.Errorf("unsupported operation %s", op)
+}
+
+func int_pow(ctx *CallContext, base, exponent *big.Int) (Object, error) {
+	if exponent.Sign() < 0 {
+		// the truncated value of 1/base^n is zero for every |base| > 1
+		switch {
+		case base.Sign() == 0:
+			return nil, ErrDivisionByZero
+		case base.CmpAbs(big.NewInt(1)) == 0:
+			if base.Sign() < 0 && exponent.Bit(0) == 1 {
+				return big_int(-1), nil
+			}
+			return big_int(1), nil
+		}
+		return big_int(0), nil
+	}
+
+	// any base other than 0 and ±1 overflows the word long before such
+	// exponents, only the low bits are worth computing
+	if base.CmpAbs(big.NewInt(1)) > 0 && exponent.Cmp(big.NewInt(int64(ctx.Word.Bits))) > 0 {
+		if ctx.Overflow == Trap {
+			return nil, OverflowError{Value: new(big.Int).Exp(base, big.NewInt(int64(ctx.Word.Bits)+1), nil), Word: ctx.Word}
+		}
+
+		modulus := new(big.Int).Lsh(big.NewInt(1), uint(ctx.Word.Bits))
+		positive := new(big.Int).Mod(base, modulus)
+		return ctx.Word.wrap(positive.Exp(positive, exponent, modulus)), nil
+	}
+
+	return BigIntObject{new(big.Int).Exp(base, exponent, nil)}, nil
+}
+
+func integer_operand(op Op, obj Object) (*big.Int, error) {
+	value, ok := exact_int(obj)
+	if !ok {
+		return nil, fmt.Errorf("operation %s expects integer operands but got %s", op, obj)
+	}
+
+	return value, nil
+}
+
+// bitwise_op implements & | xor << and >> for every numeric mode, the
+// operands have to be integers. Shifts discard the bits shifted out of the
+// word in IntegerMode regardless of the overflow mode.
+func bitwise_op(ctx *CallContext, op Op, left, right Object) (Object, error) {
+	l, err := integer_operand(op, left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := integer_operand(op, right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case OpAnd:
+		return BigIntObject{new(big.Int).And(l, r)}, nil
+	case OpOr:
+		return BigIntObject{new(big.Int).Or(l, r)}, nil
+	case OpXor:
+		return BigIntObject{new(big.Int).Xor(l, r)}, nil
+	case OpShl, OpShr:
+		if r.Sign() < 0 {
+			return nil, fmt.Errorf("operation %s expects a non-negative shift count but got %s", op, r)
+		}
+
+		limit := int64(max_exact_pow_bits)
+		if ctx.NumericMode == IntegerMode {
+			limit = int64(ctx.Word.Bits)
+		}
+
+		if op == OpShr {
+			// shifting right by the full width leaves only the sign
+			shift := uint(l.BitLen()) + 1
+			if r.IsInt64() && r.Int64() < int64(shift) {
+				shift = uint(r.Int64())
+			}
+			return BigIntObject{new(big.Int).Rsh(l, shift)}, nil
+		}
+
+		if !r.IsInt64() || r.Int64() > limit {
+			if ctx.NumericMode != IntegerMode {
+				return nil, fmt.Errorf("operation %s shift count %s is too large", op, r)
+			}
+			r = big.NewInt(limit)
+		}
+
+		result := new(big.Int).Lsh(l, uint(r.Int64()))
+		if ctx.NumericMode == IntegerMode {
+			return ctx.Word.wrap(result), nil
+		}
+		return BigIntObject{result}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported operation %s", op)
+}
+
+// bitwise_not flips every bit of an integer, within the word in IntegerMode.
+// Lists are flipped element by element.
+func bitwise_not(ctx *CallContext, obj Object) (Object, error) {
+	if list, ok := obj.(ListObject); ok {
+		values := make([]Object, len(list.Values))
+		for i, value := range list.Values {
+			result, err := bitwise_not(ctx, value)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = result
+		}
+
+		return ListObject{values}, nil
+	}
+
+	value, err := integer_operand(OpNot, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(big.Int).Not(value)
+	if ctx.NumericMode == IntegerMode {
+		return ctx.Word.wrap(result), nil
+	}
+
+	return BigIntObject{result}, nil
+}
+
+// floor_div_op implements // for every numeric mode, it divides and rounds
+// towards negative infinity.
+func floor_div_op(ctx *CallContext, left, right Object) (Object, error) {
+	l, l_ok := exact_rat(left)
+	r, r_ok := exact_rat(right)
+
+	if !l_ok || !r_ok {
+		// only non-finite floats are left, they have no exact value
+		l, err := to_float64(left)
+		if err != nil {
+			return nil, err
+		}
+		r, err := to_float64(right)
+		if err != nil {
+			return nil, err
+		}
+		return Float64Object{math.Floor(l.Value / r.Value)}, nil
+	}
+
+	if r.Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+
+	return BigIntObject{rat_floor(new(big.Rat).Quo(l, r))}, nil
+}
@@ -0,0 +1,90 @@
+package calc
+
+import "testing"
+
+func TestIntegerMode(t *testing.T) {
+	integer := []Option{WithNumericMode(IntegerMode)}
+
+	check_eval_cases(t, []eval_case{
+		{Src: "7 // 2", Opts: integer, Expected: "3"},
+		{Src: "-7 // 2", Opts: integer, Expected: "-4"},
+		{Src: "7 / 2", Opts: integer, Expected: "3"},
+		{Src: "2^10", Opts: integer, Expected: "1024"},
+		{Src: "2^-1", Opts: integer, Expected: "0"},
+		{Src: "sqrt(16)", Opts: integer, Expected: "4"},
+		{Src: "1/0", Opts: integer, Err: "division by zero"},
+		{Src: "7 // 0", Opts: integer, Err: "division by zero"},
+		{Src: "1.5 + 1", Opts: integer, Err: "'1.5' is not an integer literal"},
+	})
+}
+
+func TestBitwiseOperators(t *testing.T) {
+	integer := []Option{WithNumericMode(IntegerMode)}
+
+	check_eval_cases(t, []eval_case{
+		{Src: "0xff & 0x0f", Opts: integer, Expected: "15"},
+		{Src: "0b1010 | 0b0101", Opts: integer, Expected: "15"},
+		{Src: "6 xor 3", Opts: integer, Expected: "5"},
+		{Src: "~0", Opts: integer, Expected: "-1"},
+		{Src: "1 << 4", Opts: integer, Expected: "16"},
+		{Src: "256 >> 4", Opts: integer, Expected: "16"},
+		{Src: "-1 >> 1", Opts: integer, Expected: "-1"},
+		{Src: "1 << 64", Opts: integer, Expected: "0"},
+		// bitwise operators bind looser than sums like in C
+		{Src: "1 | 2 + 4", Opts: integer, Expected: "7"},
+		{Src: "1 << 1 + 1", Opts: integer, Expected: "4"},
+		{Src: "6 & 3 xor 1", Opts: integer, Expected: "3"},
+		// the other modes take integral operands
+		{Src: "0xff & 0x0f", Expected: "15"},
+		{Src: "1 << 3", Expected: "8"},
+		{Src: "1.5 & 1", Err: "operation And expects integer operands but got 1.5"},
+	})
+}
+
+func TestBasedLiterals(t *testing.T) {
+	integer := []Option{WithNumericMode(IntegerMode)}
+
+	check_eval_cases(t, []eval_case{
+		{Src: "0x1F", Opts: integer, Expected: "31"},
+		{Src: "0b1010", Opts: integer, Expected: "10"},
+		{Src: "0o17", Opts: integer, Expected: "15"},
+		{Src: "1_000 + 1", Opts: integer, Expected: "1001"},
+		{Src: "0xff", Expected: "255"},
+		{Src: "0x10000000000000000", Opts: []Option{WithNumericMode(RationalMode)}, Expected: "18446744073709551616"},
+	})
+}
+
+func TestWordSizes(t *testing.T) {
+	word := func(name string, overflow OverflowMode) []Option {
+		w, err := ParseWord(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []Option{WithNumericMode(IntegerMode), WithWord(w), WithOverflow(overflow)}
+	}
+
+	check_eval_cases(t, []eval_case{
+		{Src: "0x7fffffffffffffff + 1", Opts: word("i64", Wrap), Expected: "-9223372036854775808"},
+		{Src: "0x7fffffffffffffff + 1", Opts: word("i64", Trap), Err: "integer overflow, 9223372036854775808 does not fit into i64"},
+		{Src: "0 - 1", Opts: word("u64", Wrap), Expected: "18446744073709551615"},
+		{Src: "255 + 1", Opts: word("u8", Wrap), Expected: "0"},
+		{Src: "100 * 3", Opts: word("u8", Wrap), Expected: "44"},
+		{Src: "~0", Opts: word("u8", Wrap), Expected: "255"},
+		{Src: "-1", Opts: word("u8", Wrap), Expected: "255"},
+		{Src: "255 + 1", Opts: word("u8", Trap), Err: "integer overflow, 256 does not fit into u8"},
+		{Src: "~0", Opts: word("u8", Trap), Expected: "255"},
+		{Src: "127 + 1", Opts: word("i8", Wrap), Expected: "-128"},
+		{Src: "-128 - 1", Opts: word("i8", Wrap), Expected: "127"},
+		{Src: "-128 - 1", Opts: word("i8", Trap), Err: "integer overflow, -129 does not fit into i8"},
+		{Src: "40000", Opts: word("i16", Wrap), Expected: "-25536"},
+		{Src: "1 << 31", Opts: word("u32", Wrap), Expected: "2147483648"},
+		// shifts drop the bits shifted out even when overflows fail
+		{Src: "1 << 31", Opts: word("i32", Trap), Expected: "-2147483648"},
+	})
+
+	for _, name := range []string{"i12", "u", "x8", "i128"} {
+		if _, err := ParseWord(name); err == nil {
+			t.Errorf("ParseWord(%q) did not fail", name)
+		}
+	}
+}
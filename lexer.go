@@ -1,8 +1,10 @@
-package main
+package calc
 
 import (
 	"fmt"
+	"strconv"
 	"unicode"
+	"unicode/utf8"
 )
 
 type token_type int
@@ -15,13 +17,32 @@ const (
 	StarToken
 	PercentToken
 	CaretToken
+	BangToken
+	DoubleSlashToken
+	AmpersandToken
+	PipeToken
+	TildeToken
+	ShiftLeftToken
+	ShiftRightToken
+	XorToken
+	ToToken
+	InToken
+	OfToken
+	OffToken
 	OpenParensToken
 	CloseParensToken
+	OpenBracketToken
+	CloseBracketToken
 	CommaToken
+	SemicolonToken
+	RootToken
 
 	NumberToken
 	IdentifierToken
+	SuperscriptToken
 	IllegalToken
+	InvalidNumberToken
+	UnterminatedCommentToken
 )
 
 type Location struct {
@@ -29,30 +50,53 @@ type Location struct {
 	Col  int
 }
 
+// Token is a lexed token, Start and End are the byte offsets of its source
+// text with End exclusive and Location is where its first rune is.
 type Token struct {
 	TokenType token_type
 	Literal   string
 	Location  Location
+	Start     int
+	End       int
 }
 
 var token_map = map[token_type]string{
-	NumberToken:       "Number",
-	IllegalToken:      "Illegal",
-	IdentifierToken:   "Identifier",
-	EOFToken:          "EOF",
-	CommaToken:        ",",
-	PlusToken:         "+",
-	MinusToken:        "-",
-	ForwardSlashToken: "/",
-	StarToken:         "*",
-	PercentToken:      "%",
-	CaretToken:        "^",
-	OpenParensToken:   "(",
-	CloseParensToken:  ")",
+	NumberToken:              "Number",
+	IllegalToken:             "Illegal",
+	InvalidNumberToken:       "InvalidNumber",
+	SuperscriptToken:         "Superscript",
+	UnterminatedCommentToken: "UnterminatedComment",
+	IdentifierToken:          "Identifier",
+	EOFToken:                 "EOF",
+	CommaToken:               ",",
+	PlusToken:                "+",
+	MinusToken:               "-",
+	ForwardSlashToken:        "/",
+	StarToken:                "*",
+	PercentToken:             "%",
+	CaretToken:               "^",
+	BangToken:                "!",
+	DoubleSlashToken:         "//",
+	AmpersandToken:           "&",
+	PipeToken:                "|",
+	TildeToken:               "~",
+	ShiftLeftToken:           "<<",
+	ShiftRightToken:          ">>",
+	XorToken:                 "xor",
+	ToToken:                  "to",
+	InToken:                  "in",
+	OfToken:                  "of",
+	OffToken:                 "off",
+	SemicolonToken:           ";",
+	OpenParensToken:          "(",
+	CloseParensToken:         ")",
+	OpenBracketToken:         "[",
+	CloseBracketToken:        "]",
+	RootToken:                "√",
 }
 
 func (t Token) String() string {
-	if t.TokenType > CommaToken {
+	if t.TokenType > RootToken {
 		return fmt.Sprintf("%s(%s)", token_map[t.TokenType], t.Literal)
 	}
 
@@ -60,15 +104,23 @@ func (t Token) String() string {
 }
 
 type Lexer struct {
-	runes         []rune
-	offset        int
-	current_token Token
-	location      Location
+	runes       []rune
+	offset      int
+	byte_offset int
+	location    Location
+	// start and start_byte are where the token being lexed begins
+	start      Location
+	start_byte int
+	locale     Locale
+	// previous is the type of the last token, a minus after the end of an
+	// operand subtracts rather than signing the number that follows
+	previous token_type
 }
 
-func NewLexer(input []byte) *Lexer {
+func NewLexer(input []byte, locale Locale) *Lexer {
 	return &Lexer{
-		runes: []rune(string(input)),
+		runes:  []rune(string(input)),
+		locale: locale,
 		location: Location{
 			Line: 1,
 			Col:  0,
@@ -92,132 +144,476 @@ func (l Lexer) next_rune() rune {
 	return l.runes[l.offset+1]
 }
 
+func (l Lexer) peek_rune(n int) rune {
+	if l.offset+n >= len(l.runes) {
+		return eof_rune
+	}
+	return l.runes[l.offset+n]
+}
+
 func (l *Lexer) advance() {
+	if l.offset < len(l.runes) {
+		l.byte_offset += utf8.RuneLen(l.runes[l.offset])
+	}
 	l.offset++
 	l.location.Col++
 }
 
+// create_token locates tokens at the column of their first rune, columns
+// count from one.
 func (l Lexer) create_token(typ token_type, literal string) Token {
 	return Token{
 		TokenType: typ,
 		Literal:   literal,
-		Location:  l.location,
+		Location:  Location{Line: l.start.Line, Col: l.start.Col + 1},
+		Start:     l.start_byte,
+		End:       l.byte_offset,
 	}
 }
 
-func (l *Lexer) Next() Token {
+// Tokenize lexes the whole input, the tokens end with a single EOF token.
+func (l *Lexer) Tokenize() []Token {
+	tokens := []Token{}
+
+	for {
+		token := l.scan()
+		tokens = append(tokens, token)
+		l.previous = token.TokenType
+		if token.TokenType == EOFToken {
+			return tokens
+		}
+	}
+}
+
+// ends_operand reports whether a token can be the last one of an operand, like
+// the 2 of 2-1, the ) of (x)-1 or the ! of 3!-1.
+func ends_operand(token token_type) bool {
+	switch token {
+	case NumberToken, IdentifierToken, SuperscriptToken, CloseParensToken, CloseBracketToken, BangToken:
+		return true
+	}
+
+	return false
+}
+
+// scan reads the next token. Unicode operators are read as their ascii
+// counterparts, × and · multiply, ÷ divides and − subtracts, and the names π,
+// τ and ∞ are read as pi, tau and inf.
+func (l *Lexer) scan() Token {
 	current_rune := l.current_rune()
+	l.start = l.location
+	l.start_byte = l.byte_offset
 
 	switch current_rune {
 	case '+':
 		l.advance()
-		l.current_token = l.create_token(PlusToken, "+")
-		return l.current_token
-	case '-':
-		if unicode.IsNumber(l.next_rune()) {
+		return l.create_token(PlusToken, "+")
+	case '-', '−':
+		if ends_operand(l.previous) {
+			l.advance()
+			return l.create_token(MinusToken, "-")
+		}
+		if is_decimal_digit(l.next_rune()) || (l.next_rune() == l.locale.Decimal && is_decimal_digit(l.peek_rune(2))) {
 			return l.lex_number()
 		} else {
 			l.advance()
-			l.current_token = l.create_token(MinusToken, "-")
-			return l.current_token
+			return l.create_token(MinusToken, "-")
 		}
 	case '/':
+		if l.next_rune() == '*' {
+			if !l.skip_comment() {
+				return l.create_token(UnterminatedCommentToken, "/*")
+			}
+			return l.scan()
+		}
+		if l.next_rune() == '/' && (l.at_line_start() || !ends_operand(l.previous)) {
+			l.skip_comment()
+			return l.scan()
+		}
+		l.advance()
+		if l.current_rune() == '/' {
+			l.advance()
+			return l.create_token(DoubleSlashToken, "//")
+		}
+		return l.create_token(ForwardSlashToken, "/")
+	case '÷':
 		l.advance()
-		l.current_token = l.create_token(ForwardSlashToken, "/")
-		return l.current_token
+		return l.create_token(ForwardSlashToken, "/")
+	case '#':
+		l.skip_comment()
+		return l.scan()
+	case '×', '·':
+		l.advance()
+		return l.create_token(StarToken, "*")
+	case '√':
+		l.advance()
+		return l.create_token(RootToken, "√")
+	case 'π', 'τ', '∞':
+		l.advance()
+		return l.create_token(IdentifierToken, unicode_names[current_rune])
 	case '*':
 		l.advance()
-		l.current_token = l.create_token(StarToken, "*")
-		return l.current_token
+		return l.create_token(StarToken, "*")
 	case '%':
 		l.advance()
-		l.current_token = l.create_token(PercentToken, "%")
-		return l.current_token
+		return l.create_token(PercentToken, "%")
 	case '^':
 		l.advance()
-		l.current_token = l.create_token(CaretToken, "^")
-		return l.current_token
+		return l.create_token(CaretToken, "^")
+	case '!':
+		l.advance()
+		return l.create_token(BangToken, "!")
+	case '&':
+		l.advance()
+		return l.create_token(AmpersandToken, "&")
+	case '|':
+		l.advance()
+		return l.create_token(PipeToken, "|")
+	case '~':
+		l.advance()
+		return l.create_token(TildeToken, "~")
+	case '<', '>':
+		if l.next_rune() != current_rune {
+			l.advance()
+			return l.create_token(IllegalToken, string(current_rune))
+		}
+		l.advance()
+		l.advance()
+		if current_rune == '<' {
+			return l.create_token(ShiftLeftToken, "<<")
+		}
+		return l.create_token(ShiftRightToken, ">>")
 	case '(':
 		l.advance()
-		l.current_token = l.create_token(OpenParensToken, "(")
-		return l.current_token
+		return l.create_token(OpenParensToken, "(")
 	case ')':
 		l.advance()
-		l.current_token = l.create_token(CloseParensToken, "(")
-		return l.current_token
+		return l.create_token(CloseParensToken, ")")
+	case '[':
+		l.advance()
+		return l.create_token(OpenBracketToken, "[")
+	case ']':
+		l.advance()
+		return l.create_token(CloseBracketToken, "]")
 	case ',':
+		if l.locale.Decimal == ',' && is_decimal_digit(l.next_rune()) {
+			return l.lex_number()
+		}
 		l.advance()
-		l.current_token = l.create_token(CommaToken, ",")
-		return l.current_token
-	case eof_rune, 0:
+		return l.create_token(CommaToken, ",")
+	case ';':
 		l.advance()
-		l.current_token = l.create_token(EOFToken, "")
-		return l.current_token
+		return l.create_token(SemicolonToken, ";")
+	case '.':
+		if l.locale.Decimal == '.' && is_decimal_digit(l.next_rune()) {
+			return l.lex_number()
+		}
+		return l.lex_identifier()
+	case eof_rune, 0:
+		return l.create_token(EOFToken, "")
 	default:
 		if unicode.IsSpace(current_rune) {
 			l.skip_whitespace()
-			return l.Next()
-		} else if unicode.IsNumber(current_rune) {
+			return l.scan()
+		} else if is_decimal_digit(current_rune) {
 			return l.lex_number()
+		} else if _, ok := superscripts[current_rune]; ok {
+			return l.lex_superscript()
 		} else {
 			return l.lex_identifier()
 		}
 	}
 }
 
-func (l Lexer) CurrentToken() Token {
-	return l.current_token
+// TokenStream hands out the tokens of an input one by one and lets the parser
+// look ahead of the current one with Peek.
+type TokenStream struct {
+	tokens   []Token
+	position int
 }
 
-func (l *Lexer) Prev() {
-	size := len(l.current_token.Literal)
-	l.offset -= size
-	l.location.Col -= size
+func NewTokenStream(input []byte, locale Locale) *TokenStream {
+	return &TokenStream{tokens: NewLexer(input, locale).Tokenize()}
+}
+
+// Peek returns the nth token after the current one without consuming it,
+// Peek(0) is the token Next returns. Looking past the end gives the EOF
+// token.
+func (s *TokenStream) Peek(n int) Token {
+	if s.position+n >= len(s.tokens) {
+		return s.tokens[len(s.tokens)-1]
+	}
+	return s.tokens[s.position+n]
+}
+
+// Next consumes the current token, EOF is returned again at the end.
+func (s *TokenStream) Next() Token {
+	token := s.Peek(0)
+	if s.position < len(s.tokens)-1 {
+		s.position++
+	}
+	return token
+}
+
+// Skip consumes n tokens.
+func (s *TokenStream) Skip(n int) {
+	for range n {
+		s.Next()
+	}
 }
 
 func (l *Lexer) skip_whitespace() {
 	for unicode.IsSpace(l.current_rune()) {
-		if l.current_rune() == '\r' || l.current_rune() == '\n' {
-			l.location.Line++
-			l.location.Col = 0
+		l.skip_rune()
+	}
+}
+
+// at_line_start reports whether only blanks come before the current rune on
+// its line.
+func (l Lexer) at_line_start() bool {
+	for i := l.offset - 1; i >= 0; i-- {
+		switch l.runes[i] {
+		case '\n':
+			return true
+		case ' ', '\t', '\r':
+			continue
+		}
+		return false
+	}
+
+	return true
+}
+
+// skip_rune advances past a rune that may end a line.
+func (l *Lexer) skip_rune() {
+	if l.current_rune() == '\n' {
+		l.advance()
+		l.location.Line++
+		l.location.Col = 0
+		return
+	}
+	l.advance()
+}
+
+// skip_comment skips a # or // comment up to the end of the line, or a /* */
+// comment which may span lines. It reports false for a block comment that is
+// never closed.
+func (l *Lexer) skip_comment() bool {
+	if l.current_rune() == '/' && l.next_rune() == '*' {
+		l.advance()
+		l.advance()
+		for l.current_rune() != eof_rune {
+			if l.current_rune() == '*' && l.next_rune() == '/' {
+				l.advance()
+				l.advance()
+				return true
+			}
+			l.skip_rune()
+		}
+		return false
+	}
+
+	for l.current_rune() != eof_rune && l.current_rune() != '\n' {
+		l.advance()
+	}
+	return true
+}
+
+// unicode_names are the names written with a single symbol.
+var unicode_names = map[rune]string{
+	'π': "pi",
+	'τ': "tau",
+	'∞': "inf",
+}
+
+// superscripts are the exponent runes, x² is read as x^2.
+var superscripts = map[rune]rune{
+	'⁰': '0',
+	'¹': '1',
+	'²': '2',
+	'³': '3',
+	'⁴': '4',
+	'⁵': '5',
+	'⁶': '6',
+	'⁷': '7',
+	'⁸': '8',
+	'⁹': '9',
+	'⁻': '-',
+}
+
+// lex_superscript reads a run of superscript runes, the parser turns it into
+// a power.
+func (l *Lexer) lex_superscript() Token {
+	start := l.offset
+
+	for {
+		if _, ok := superscripts[l.current_rune()]; !ok {
+			break
 		}
 		l.advance()
 	}
+
+	return l.create_token(SuperscriptToken, string(l.runes[start:l.offset]))
+}
+
+// superscript_value returns the integer a superscript literal like ⁻¹ spells.
+func superscript_value(literal string) (int, error) {
+	digits := []rune{}
+	for _, r := range literal {
+		digits = append(digits, superscripts[r])
+	}
+
+	return strconv.Atoi(string(digits))
 }
 
+// lex_number reads number literals:
+//
+//	number   = ["-"] mantissa ([exponent] ["i"] | suffix) .
+//	number   = ["-"] "0" ("x" | "b" | "o") based_digits .
+//	mantissa = integer [point [digits]] | point digits .
+//	integer  = digits { group digit digit digit } .
+//	digits   = digit { ["_"] digit } .
+//	exponent = ("e" | "E") ["+" | "-"] digits .
+//	suffix   = "T" | "G" | "M" | "k" | "u" | "µ" | "n" | "p" | "f" .
+//
+// An e only starts an exponent when digits follow it and suffixes and the
+// imaginary i only count when they are not the start of a name, so 2e is two
+// followed by the constant e and 5mi is five miles. Suffixes have to touch
+// the digits, 4.7k is 4700 while 4.7 k is 4.7 times k. A literal running into a
+// dot, an underscore or more digits is malformed and lexed as an
+// InvalidNumberToken.
+//
+// The point and group are the decimal and group separators of the locale,
+// groups are only read when the group separator is not also the argument
+// separator. The literal of the token is written with a "." and without
+// groups.
 func (l *Lexer) lex_number() Token {
-	value := []rune{}
+	start := l.offset
 
-	if l.current_rune() == '-' {
+	if l.current_rune() == '-' || l.current_rune() == '−' {
 		l.advance()
-		value = append(value, '-')
 	}
 
-	for unicode.IsNumber(l.current_rune()) {
-		value = append(value, l.current_rune())
+	if l.current_rune() == '0' && is_base_digit(base_prefix(l.next_rune()), l.peek_rune(2)) {
+		base := base_prefix(l.next_rune())
+		l.advance()
 		l.advance()
+		l.lex_digits(func(r rune) bool { return is_base_digit(base, r) })
+
+		return l.number_token(start)
+	}
+
+	l.lex_digits(is_decimal_digit)
+	for l.locale.groups_literals() && l.current_rune() == l.locale.Group && l.digit_group() {
+		l.advance()
+		l.lex_digits(is_decimal_digit)
+	}
+
+	if l.current_rune() == l.locale.Decimal {
+		l.advance()
+		l.lex_digits(is_decimal_digit)
+	}
+
+	exponent := l.current_rune() == 'e' || l.current_rune() == 'E'
+	sign := l.next_rune() == '+' || l.next_rune() == '-'
+	if exponent && (is_decimal_digit(l.next_rune()) || (sign && is_decimal_digit(l.peek_rune(2)))) {
+		l.advance()
+		if sign {
+			l.advance()
+		}
+		l.lex_digits(is_decimal_digit)
+	} else if l.suffix() {
+		l.advance()
+		return l.number_token(start)
 	}
 
-	if l.current_rune() == '.' {
+	// an i right after the digits makes the number imaginary, as in 4i
+	if l.current_rune() == 'i' && !identifier_minor(l.next_rune()) {
 		l.advance()
-		value = append(value, '.')
 	}
 
-	for unicode.IsNumber(l.current_rune()) {
-		value = append(value, l.current_rune())
+	return l.number_token(start)
+}
+
+// lex_digits reads digits that may be grouped with single underscores, like
+// 1_000_000.
+func (l *Lexer) lex_digits(is_digit func(rune) bool) {
+	for is_digit(l.current_rune()) || (l.current_rune() == '_' && is_digit(l.next_rune())) {
 		l.advance()
 	}
+}
+
+// digit_group reports whether the group separator at the current rune is
+// followed by exactly three digits.
+func (l Lexer) digit_group() bool {
+	for i := 1; i <= 3; i++ {
+		if !is_decimal_digit(l.peek_rune(i)) {
+			return false
+		}
+	}
 
-	l.current_token = l.create_token(NumberToken, string(value))
-	return l.current_token
+	return !is_decimal_digit(l.peek_rune(4)) && l.peek_rune(4) != '_'
+}
+
+// number_token creates the token of the number literal lexed since start, or
+// an InvalidNumberToken holding the whole malformed literal.
+func (l *Lexer) number_token(start int) Token {
+	current := l.current_rune()
+	if current == '.' || current == l.locale.Decimal || current == '_' || is_decimal_digit(current) {
+		for identifier_minor(l.current_rune()) || l.current_rune() == '.' || l.current_rune() == l.locale.Decimal {
+			l.advance()
+		}
+
+		return l.create_token(InvalidNumberToken, string(l.runes[start:l.offset]))
+	}
+
+	literal := []rune{}
+	for _, r := range l.runes[start:l.offset] {
+		switch {
+		case r == l.locale.Decimal:
+			literal = append(literal, '.')
+		case r == l.locale.Group:
+		default:
+			literal = append(literal, r)
+		}
+	}
+
+	return l.create_token(NumberToken, string(literal))
+}
+
+// si_suffixes are the engineering suffixes of number literals and the power
+// of ten they stand for, 4.7k is 4700. There is no milli suffix, 5m is five
+// metres.
+var si_suffixes = map[rune]int{
+	'T': 12,
+	'G': 9,
+	'M': 6,
+	'k': 3,
+	'u': -6,
+	'µ': -6,
+	'n': -9,
+	'p': -12,
+	'f': -15,
+}
+
+// suffix reports whether the current rune is an engineering suffix of the
+// number before it. Letters that are units on their own are read as the unit,
+// so 5m and 5 m are both five metres.
+func (l Lexer) suffix() bool {
+	current := l.current_rune()
+	if _, ok := si_suffixes[current]; !ok || identifier_minor(l.next_rune()) {
+		return false
+	}
+
+	_, unit := lookup_unit(string(current))
+	return !unit
 }
 
 func (l *Lexer) lex_identifier() Token {
 	current := l.current_rune()
 	if !identifier_major(current) {
 		l.advance()
-		l.current_token = Illegal
-		return l.current_token
+		return l.create_token(IllegalToken, string(current))
 	}
 
 	value := []rune{}
@@ -228,8 +624,52 @@ func (l *Lexer) lex_identifier() Token {
 		current = l.current_rune()
 	}
 
-	l.current_token = l.create_token(IdentifierToken, string(value))
-	return l.current_token
+	switch string(value) {
+	case "xor":
+		return l.create_token(XorToken, "xor")
+	case "to":
+		return l.create_token(ToToken, "to")
+	case "in":
+		return l.create_token(InToken, "in")
+	case "of":
+		return l.create_token(OfToken, "of")
+	case "off":
+		return l.create_token(OffToken, "off")
+	}
+
+	return l.create_token(IdentifierToken, string(value))
+}
+
+// base_prefix returns the base of the 0x, 0b and 0o literal prefixes, or zero
+// for any other rune.
+func base_prefix(r rune) int {
+	switch r {
+	case 'x', 'X':
+		return 16
+	case 'b', 'B':
+		return 2
+	case 'o', 'O':
+		return 8
+	}
+
+	return 0
+}
+
+func is_base_digit(base int, r rune) bool {
+	switch base {
+	case 2:
+		return r == '0' || r == '1'
+	case 8:
+		return r >= '0' && r <= '7'
+	case 16:
+		return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+	}
+
+	return false
+}
+
+func is_decimal_digit(r rune) bool {
+	return r >= '0' && r <= '9'
 }
 
 func identifier_major(r rune) bool {
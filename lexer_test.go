@@ -0,0 +1,100 @@
+package calc
+
+import (
+	"strings"
+	"testing"
+)
+
+// eval_case is an expression and either the result it prints or a part of
+// the error it fails with.
+type eval_case struct {
+	Src      string
+	Opts     []Option
+	Expected string
+	Err      string
+}
+
+func check_eval_cases(t *testing.T, cases []eval_case) {
+	t.Helper()
+
+	for _, test := range cases {
+		value, err := Eval(test.Src, test.Opts...)
+
+		if test.Err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.Err) {
+				t.Errorf("%s: got error %v, want one containing %q", test.Src, err, test.Err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", test.Src, err)
+			continue
+		}
+
+		if value.String() != test.Expected {
+			t.Errorf("%s = %s, want %s", test.Src, value, test.Expected)
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "4.7k", Expected: "4700"},
+		{Src: "2.5M", Expected: "2.5e+06"},
+		{Src: "3m", Expected: "3 m"},
+		{Src: "3 m", Expected: "3 m"},
+		{Src: "5km + 300m", Expected: "5.3 km"},
+		{Src: "1e400", Err: "number literal '1e400' is out of range at 1:1"},
+		{Src: "1e400i", Opts: []Option{WithNumericMode(RationalMode)}, Err: "out of range"},
+		{Src: "1e400 / 1e399", Opts: []Option{WithNumericMode(RationalMode)}, Expected: "10"},
+		{Src: "1e400 / 1e399", Opts: []Option{WithNumericMode(BigMode)}, Expected: "10"},
+	})
+}
+
+func TestMinusAfterOperands(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "1-2", Expected: "-1"},
+		{Src: "2pi-1", Expected: "5.283185307179586"},
+		{Src: "3!-1", Expected: "5"},
+		{Src: "(4)-1", Expected: "3"},
+		{Src: "[1, 2][1]-1", Expected: "1"},
+		{Src: "3²-1", Expected: "8"},
+		{Src: "-2+3", Expected: "1"},
+		{Src: "2*-3", Expected: "-6"},
+		{Src: "2^-1", Expected: "0.5"},
+		{Src: "1 - -1", Expected: "2"},
+		{Src: "max(-1, -2)", Expected: "-1"},
+		{Src: "1e-3", Expected: "0.001"},
+	})
+
+	tokens := NewLexer([]byte("x-1"), DefaultLocale).Tokenize()
+	types := []token_type{IdentifierToken, MinusToken, NumberToken, EOFToken}
+	if len(tokens) != len(types) {
+		t.Fatalf("x-1 lexes as %v", tokens)
+	}
+	for i, token := range tokens {
+		if token.TokenType != types[i] {
+			t.Errorf("x-1 lexes as %v", tokens)
+			break
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "1 + 2 # three", Expected: "3"},
+		{Src: "1 + /* two */ 2", Expected: "3"},
+		{Src: "1 /* two\nlines */ + 2", Expected: "3"},
+		{Src: "1 + /* open", Err: "unterminated block comment"},
+		{Src: "// the answer\n42", Expected: "42"},
+		{Src: "  // indented\n42", Expected: "42"},
+		{Src: "1 + // one more\n1", Expected: "2"},
+		{Src: "max(// first\n1, 2)", Expected: "2"},
+		{Src: "7 // 2", Expected: "3"},
+		{Src: "7//2", Expected: "3"},
+		{Src: "(7) // 2", Expected: "3"},
+		{Src: "7\n// 2", Expected: "7"},
+		{Src: "7 // 2 # halved", Expected: "3"},
+	})
+}
@@ -0,0 +1,315 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"slices"
+	"strings"
+)
+
+// ListObject is an ordered list of values. The vm only holds a reference to
+// the backing slice, lists are never modified once they are built.
+type ListObject struct {
+	Values []Object
+}
+
+func (obj ListObject) GetValue() interface{} {
+	return obj.Values
+}
+
+func (obj ListObject) String() string {
+	return obj.format(func(value Object) string {
+		return value.String()
+	}, ", ")
+}
+
+func (obj ListObject) format(element func(Object) string, separator string) string {
+	values := make([]string, len(obj.Values))
+	for i, value := range obj.Values {
+		values[i] = element(value)
+	}
+
+	return fmt.Sprintf("[%s]", strings.Join(values, separator))
+}
+
+func any_list(args []Object) bool {
+	for _, arg := range args {
+		if _, ok := arg.(ListObject); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// list_op applies a binary operation element by element. Two lists need the
+// same length, a scalar operand is broadcast to every element of the list.
+// Multiplying two lists of which one is a matrix is a matrix product.
+func list_op(ctx *CallContext, op Op, left, right Object) (Object, error) {
+	l, l_list := left.(ListObject)
+	r, r_list := right.(ListObject)
+	if op == OpMul && l_list && r_list && (is_matrix(l) || is_matrix(r)) {
+		return matrix_product(ctx, l, r)
+	}
+
+	length, err := common_length([]Object{left, right})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Object, length)
+	for i := range result {
+		value, err := binary_op(ctx, op, list_element(left, i), list_element(right, i))
+		if err != nil {
+			return nil, err
+		}
+		result[i] = value
+	}
+
+	return ListObject{result}, nil
+}
+
+// common_length returns the length shared by every list among the values,
+// scalars fit any length.
+func common_length(values []Object) (int, error) {
+	length := -1
+
+	for _, value := range values {
+		list, ok := value.(ListObject)
+		if !ok {
+			continue
+		}
+
+		if length >= 0 && len(list.Values) != length {
+			return 0, DimensionError{Reason: fmt.Sprintf("cannot broadcast lists of different lengths %d and %d", length, len(list.Values))}
+		}
+		length = len(list.Values)
+	}
+
+	return length, nil
+}
+
+// list_element returns the i'th element of a list, or the value itself for
+// scalars that are broadcast.
+func list_element(value Object, i int) Object {
+	if list, ok := value.(ListObject); ok {
+		return list.Values[i]
+	}
+
+	return value
+}
+
+// list_index returns the element at index, negative indices count from the
+// end of the list.
+func list_index(value, index Object) (Object, error) {
+	list, ok := value.(ListObject)
+	if !ok {
+		return nil, fmt.Errorf("cannot index %s, it is not a list", value)
+	}
+
+	n, ok := exact_int(index)
+	if !ok {
+		return nil, fmt.Errorf("list indices must be integers but got %s", index)
+	}
+
+	i := n.Int64()
+	if i < 0 {
+		i += int64(len(list.Values))
+	}
+
+	if !n.IsInt64() || i < 0 || i >= int64(len(list.Values)) {
+		return nil, fmt.Errorf("index %s out of range for a list of length %d", n, len(list.Values))
+	}
+
+	return list.Values[i], nil
+}
+
+// flatten_lists spreads list arguments into the argument list of variadic
+// builtins, so mean([1, 2, 3]) is mean(1, 2, 3).
+func flatten_lists(args []Object) []Object {
+	result := []Object{}
+
+	for _, arg := range args {
+		if list, ok := arg.(ListObject); ok {
+			result = append(result, flatten_lists(list.Values)...)
+		} else {
+			result = append(result, arg)
+		}
+	}
+
+	return result
+}
+
+// map_list_call calls a builtin once per element of its list arguments,
+// broadcasting scalar arguments the same way list_op does.
+func (d BuiltinFnDescriptor) map_list_call(ctx *CallContext, args []Object) (Object, error) {
+	length, err := common_length(args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Object, length)
+	for i := range result {
+		element_args := make([]Object, len(args))
+		for j, arg := range args {
+			element_args[j] = list_element(arg, i)
+		}
+
+		value, err := d.Call(ctx, element_args)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = value
+	}
+
+	return ListObject{result}, nil
+}
+
+func list_arg(name string, arg Object) (ListObject, error) {
+	list, ok := arg.(ListObject)
+	if !ok {
+		return ListObject{}, DomainError{Name: name, Reason: fmt.Sprintf("expects a list but got %s", arg)}
+	}
+
+	return list, nil
+}
+
+// dot_product sums the products of the elements of two lists of the same
+// length.
+func dot_product(ctx *CallContext, left, right ListObject) (Object, error) {
+	if len(left.Values) != len(right.Values) {
+		return nil, DomainError{Name: "dot", Reason: fmt.Sprintf("expects lists of the same length but got %d and %d", len(left.Values), len(right.Values))}
+	}
+
+	var result Object = big_int(0)
+	for i := range left.Values {
+		product, err := binary_op(ctx, OpMul, left.Values[i], right.Values[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if result, err = binary_op(ctx, OpAdd, result, product); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// euclidean_norm is the square root of the sum of the squared magnitudes of
+// the elements, exact for integers and rationals when the root is.
+func euclidean_norm(ctx *CallContext, v ListObject) (Object, error) {
+	var sum Object = big_int(0)
+
+	for _, value := range v.Values {
+		var square Object
+		var err error
+
+		// |z|² keeps complex elements real
+		if z, ok := value.(ComplexObject); ok {
+			square = Float64Object{real(z.Value)*real(z.Value) + imag(z.Value)*imag(z.Value)}
+		} else if square, err = binary_op(ctx, OpMul, value, value); err != nil {
+			return nil, err
+		}
+
+		if sum, err = binary_op(ctx, OpAdd, sum, square); err != nil {
+			return nil, err
+		}
+	}
+
+	if root, ok := exact_sqrt(sum); ok {
+		return root, nil
+	}
+
+	if x, ok := as_big_float(ctx, sum); ok {
+		return BigFloatObject{new_big_float(ctx.Precision).Sqrt(x)}, nil
+	}
+
+	value, err := to_float64(sum)
+	if err != nil {
+		return nil, err
+	}
+
+	return Float64Object{math.Sqrt(value.Value)}, nil
+}
+
+// factor_limit bounds the integers factor() accepts, Pollard's rho below
+// works with 64 bit arithmetic.
+var factor_limit = new(big.Int).SetUint64(1<<64 - 1)
+
+// prime_factors returns the prime factors of n in ascending order, repeated
+// by their multiplicity. Small factors are found by trial division and the
+// rest with Pollard's rho.
+func prime_factors(n uint64) []uint64 {
+	factors := []uint64{}
+
+	for p := uint64(2); p < 1000 && p*p <= n; p++ {
+		for n%p == 0 {
+			factors = append(factors, p)
+			n /= p
+		}
+	}
+
+	var split func(n uint64)
+	split = func(n uint64) {
+		if n == 1 {
+			return
+		}
+		if new(big.Int).SetUint64(n).ProbablyPrime(20) {
+			factors = append(factors, n)
+			return
+		}
+
+		d := pollard_rho(n)
+		split(d)
+		split(n / d)
+	}
+	split(n)
+
+	slices.Sort(factors)
+	return factors
+}
+
+// pollard_rho finds a non-trivial divisor of the odd composite n.
+func pollard_rho(n uint64) uint64 {
+	for c := uint64(1); ; c++ {
+		next := func(x uint64) uint64 {
+			return add_mod(mul_mod(x, x, n), c, n)
+		}
+
+		x, y, d := uint64(2), uint64(2), uint64(1)
+		for d == 1 {
+			x = next(x)
+			y = next(next(y))
+			if x > y {
+				d = gcd_uint64(x-y, n)
+			} else {
+				d = gcd_uint64(y-x, n)
+			}
+		}
+
+		if d != n {
+			return d
+		}
+	}
+}
+
+func mul_mod(a, b, m uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return bits.Rem64(hi, lo, m)
+}
+
+func add_mod(a, b, m uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	return bits.Rem64(carry, sum, m)
+}
+
+func gcd_uint64(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
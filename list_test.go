@@ -0,0 +1,82 @@
+package calc
+
+import "testing"
+
+func TestLists(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "[1, 2, 3]", Expected: "[1, 2, 3]"},
+		{Src: "[]", Expected: "[]"},
+		{Src: "[1, [2, 3]]", Expected: "[1, [2, 3]]"},
+		{Src: "[1, 2, 3][0]", Expected: "1"},
+		{Src: "[1, 2, 3][2]", Expected: "3"},
+		{Src: "[1, 2][-1]", Expected: "2"},
+		{Src: "[[1, 2], [3, 4]][1][0]", Expected: "3"},
+		{Src: "[1, 2, 3][1 + 1]", Expected: "3"},
+		{Src: "[1, 2, 3][3]", Err: "index 3 out of range for a list of length 3"},
+		{Src: "[1, 2][0.5]", Err: "list indices must be integers but got 0.5"},
+	})
+
+	german, err := ParseLocale("de_DE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check_eval_cases(t, []eval_case{
+		{Src: "[1,5; 2]", Opts: []Option{WithLocale(german)}, Expected: "[1.5, 2]"},
+	})
+}
+
+func TestListBroadcasting(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "[1, 2, 3] + 1", Expected: "[2, 3, 4]"},
+		{Src: "2 * [1, 2]", Expected: "[2, 4]"},
+		{Src: "[1, 2] + [3, 4]", Expected: "[4, 6]"},
+		{Src: "[1, 2] ^ 2", Expected: "[1, 4]"},
+		{Src: "[1, [2, 3]] * 2", Expected: "[2, [4, 6]]"},
+		{Src: "sqrt([4, 9])", Expected: "[2, 3]"},
+		{Src: "[1, 2] + [1, 2, 3]", Err: "cannot broadcast lists of different lengths 2 and 3 at 1:8"},
+		{Src: "[1, 2] / 4", Opts: []Option{WithNumericMode(RationalMode)}, Expected: "[0.25, 0.5]"},
+	})
+}
+
+func TestListBuiltins(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "sum([1, 2, 3])", Expected: "6"},
+		{Src: "sum([])", Expected: "0"},
+		{Src: "len([1, 2, 3])", Expected: "3"},
+		{Src: "len([])", Expected: "0"},
+		{Src: "dot([1, 2, 3], [4, 5, 6])", Expected: "32"},
+		{Src: "norm([3, 4])", Expected: "5"},
+		{Src: "cross([1, 0, 0], [0, 1, 0])", Expected: "[0, 0, 1]"},
+		{Src: "max([1, 5, 3])", Expected: "5"},
+		{Src: "dot([1], [1, 2])", Err: "function 'dot' expects lists of the same length but got 1 and 2"},
+		{Src: "cross([1, 2], [3, 4])", Err: "function 'cross' expects lists of three elements but got 2 and 2"},
+	})
+}
+
+// TestListConstants round trips list constants through the archive.
+func TestListConstants(t *testing.T) {
+	parser := NewParser([]byte("[1, [2.5, 3]][1] + [4, 5]"), "calc")
+	expr, err := parser.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := NewCompiler(expr).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vm, err := NewVm(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := vm.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value.String() != "[6.5, 8]" {
+		t.Errorf("got %s, want [6.5, 8]", value)
+	}
+}
@@ -0,0 +1,120 @@
+package calc
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Locale holds the separators numbers are written with. Locales with a
+// decimal comma separate arguments and list items with a semicolon instead.
+type Locale struct {
+	Name string
+	// Decimal separates the integer part of a number from its fraction.
+	Decimal rune
+	// Group separates the thousands of a number. Number literals may use it
+	// unless it is also the argument separator, 1,000 reads as 1 and 0 in
+	// the default locale.
+	Group rune
+	// Separator separates the arguments of calls and the items of lists.
+	Separator rune
+}
+
+var (
+	// DefaultLocale writes 1,000,000.5 and max(1, 2).
+	DefaultLocale = Locale{Name: "en", Decimal: '.', Group: ',', Separator: ','}
+	// comma_locale writes 1.000.000,5 and max(1; 2).
+	comma_locale = Locale{Decimal: ',', Group: '.', Separator: ';'}
+	// space_locale writes 1 000 000,5 with a narrow no-break space and
+	// max(1; 2).
+	space_locale = Locale{Decimal: ',', Group: '\u202f', Separator: ';'}
+	// apostrophe_locale writes 1'000'000.5 and max(1, 2).
+	apostrophe_locale = Locale{Decimal: '.', Group: '\'', Separator: ','}
+)
+
+// locales maps languages and regions to the locale they write numbers in, a
+// region takes precedence over its language.
+var locales = map[string]Locale{
+	"en":    DefaultLocale,
+	"ja":    DefaultLocale,
+	"zh":    DefaultLocale,
+	"ko":    DefaultLocale,
+	"he":    DefaultLocale,
+	"th":    DefaultLocale,
+	"de":    comma_locale,
+	"es":    comma_locale,
+	"it":    comma_locale,
+	"nl":    comma_locale,
+	"pt":    comma_locale,
+	"tr":    comma_locale,
+	"id":    comma_locale,
+	"da":    comma_locale,
+	"el":    comma_locale,
+	"ro":    comma_locale,
+	"fr":    space_locale,
+	"ru":    space_locale,
+	"uk":    space_locale,
+	"pl":    space_locale,
+	"cs":    space_locale,
+	"sk":    space_locale,
+	"sv":    space_locale,
+	"fi":    space_locale,
+	"nb":    space_locale,
+	"no":    space_locale,
+	"hu":    space_locale,
+	"de_CH": apostrophe_locale,
+	"it_CH": apostrophe_locale,
+}
+
+// ParseLocale looks a locale up by a name like de, de_DE or the de_DE.UTF-8
+// of the LANG environment variable. C and POSIX are the default locale.
+func ParseLocale(name string) (Locale, error) {
+	tag, _, _ := strings.Cut(name, ".")
+	tag, _, _ = strings.Cut(tag, "@")
+	tag = strings.ReplaceAll(tag, "-", "_")
+
+	if tag == "C" || tag == "POSIX" {
+		return DefaultLocale, nil
+	}
+
+	language, region, _ := strings.Cut(tag, "_")
+	language = strings.ToLower(language)
+
+	if locale, ok := locales[language+"_"+strings.ToUpper(region)]; ok {
+		locale.Name = tag
+		return locale, nil
+	}
+
+	if locale, ok := locales[language]; ok {
+		locale.Name = tag
+		return locale, nil
+	}
+
+	return DefaultLocale, fmt.Errorf("unknown locale '%s'", name)
+}
+
+// EnvironmentLocale is the locale of numbers set by the environment, LC_ALL
+// takes precedence over LC_NUMERIC which takes precedence over LANG. Unset
+// and unknown locales are the default locale.
+func EnvironmentLocale() Locale {
+	for _, variable := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		name := os.Getenv(variable)
+		if name == "" {
+			continue
+		}
+
+		locale, err := ParseLocale(name)
+		if err != nil {
+			return DefaultLocale
+		}
+		return locale
+	}
+
+	return DefaultLocale
+}
+
+// groups_literals reports whether number literals may group their digits
+// with the group separator.
+func (locale Locale) groups_literals() bool {
+	return locale.Group != locale.Separator
+}
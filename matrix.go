@@ -0,0 +1,688 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"slices"
+)
+
+// DimensionError is returned when the shapes of list or matrix operands do
+// not fit together. Location is the position of the operator or the call in
+// the source, it stays zero when the position is not known, as it is for
+// programs loaded from an archive.
+type DimensionError struct {
+	Reason   string
+	Location Location
+}
+
+func (err DimensionError) Error() string {
+	if err.Location.Line == 0 {
+		return err.Reason
+	}
+
+	return fmt.Sprintf("%s at %d:%d", err.Reason, err.Location.Line, err.Location.Col)
+}
+
+// matrix is the row major view of a list of equally long lists of numbers,
+// the representation the linear algebra builtins work on.
+type matrix [][]Object
+
+func (m matrix) rows() int {
+	return len(m)
+}
+
+func (m matrix) cols() int {
+	if len(m) == 0 {
+		return 0
+	}
+
+	return len(m[0])
+}
+
+func (m matrix) shape() string {
+	return fmt.Sprintf("%dx%d", m.rows(), m.cols())
+}
+
+func (m matrix) square() bool {
+	return m.rows() == m.cols()
+}
+
+func (m matrix) clone() matrix {
+	result := make(matrix, len(m))
+	for i, row := range m {
+		result[i] = slices.Clone(row)
+	}
+
+	return result
+}
+
+func (m matrix) object() ListObject {
+	rows := make([]Object, len(m))
+	for i, row := range m {
+		rows[i] = ListObject{row}
+	}
+
+	return ListObject{rows}
+}
+
+func new_matrix(rows, cols int, value Object) matrix {
+	result := make(matrix, rows)
+	for i := range result {
+		result[i] = make([]Object, cols)
+		for j := range result[i] {
+			result[i][j] = value
+		}
+	}
+
+	return result
+}
+
+func identity_matrix(n int) matrix {
+	result := new_matrix(n, n, big_int(0))
+	for i := range result {
+		result[i][i] = big_int(1)
+	}
+
+	return result
+}
+
+// is_matrix reports lists made of lists, the operands * multiplies as
+// matrices instead of element by element.
+func is_matrix(obj Object) bool {
+	list, ok := obj.(ListObject)
+	if !ok || len(list.Values) == 0 {
+		return false
+	}
+
+	for _, row := range list.Values {
+		if _, ok := row.(ListObject); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// as_matrix reads a list of lists as a matrix, every row needs the same
+// length and every element has to be a number.
+func as_matrix(list ListObject) (matrix, error) {
+	result := make(matrix, len(list.Values))
+
+	for i, value := range list.Values {
+		row, ok := value.(ListObject)
+		if !ok {
+			return nil, fmt.Errorf("cannot use %s as a matrix row", value)
+		}
+
+		if i > 0 && len(row.Values) != len(result[0]) {
+			return nil, DimensionError{Reason: fmt.Sprintf("matrix rows have different lengths %d and %d", len(result[0]), len(row.Values))}
+		}
+
+		for _, element := range row.Values {
+			if _, ok := element.(ListObject); ok {
+				return nil, fmt.Errorf("cannot use %s as a matrix element", element)
+			}
+		}
+
+		result[i] = row.Values
+	}
+
+	return result, nil
+}
+
+// matrix_arg is the matrix argument of the builtin name.
+func matrix_arg(name string, arg Object) (matrix, error) {
+	if !is_matrix(arg) {
+		return nil, DomainError{Name: name, Reason: fmt.Sprintf("expects a matrix but got %s", arg)}
+	}
+
+	return as_matrix(arg.(ListObject))
+}
+
+// square_matrix_arg is the square matrix argument of the builtin name.
+func square_matrix_arg(name string, arg Object) (matrix, error) {
+	m, err := matrix_arg(name, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	if !m.square() {
+		return nil, DimensionError{Reason: fmt.Sprintf("function '%s' expects a square matrix but got a %s matrix", name, m.shape())}
+	}
+
+	return m, nil
+}
+
+// matrix_product multiplies two lists of which at least one is a matrix. A
+// plain list on the left is a row vector and one on the right a column
+// vector, the product of a matrix and a vector is a plain list again.
+func matrix_product(ctx *CallContext, left, right ListObject) (Object, error) {
+	a, left_vector, err := matrix_operand(left, true)
+	if err != nil {
+		return nil, err
+	}
+
+	b, right_vector, err := matrix_operand(right, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.rows() == 0 || a.cols() == 0 || b.rows() == 0 || b.cols() == 0 {
+		return nil, DimensionError{Reason: fmt.Sprintf("cannot multiply a %s matrix by a %s matrix, both need at least one element", a.shape(), b.shape())}
+	}
+
+	if a.cols() != b.rows() {
+		return nil, DimensionError{Reason: fmt.Sprintf("cannot multiply a %s matrix by a %s matrix", a.shape(), b.shape())}
+	}
+
+	arith := &element_arith{ctx: ctx}
+	result := new_matrix(a.rows(), b.cols(), big_int(0))
+	for i := range result {
+		for j := range result[i] {
+			for k := range b {
+				result[i][j] = arith.op(OpAdd, result[i][j], arith.op(OpMul, a[i][k], b[k][j]))
+			}
+		}
+	}
+
+	if arith.err != nil {
+		return nil, arith.err
+	}
+
+	switch {
+	case left_vector:
+		return ListObject{result[0]}, nil
+	case right_vector:
+		column := make([]Object, result.rows())
+		for i, row := range result {
+			column[i] = row[0]
+		}
+		return ListObject{column}, nil
+	}
+
+	return result.object(), nil
+}
+
+// matrix_operand reads an operand of a matrix product, plain lists become a
+// single row on the left and a single column on the right.
+func matrix_operand(list ListObject, left bool) (matrix, bool, error) {
+	if is_matrix(list) {
+		m, err := as_matrix(list)
+		return m, false, err
+	}
+
+	if left {
+		return matrix{list.Values}, true, nil
+	}
+
+	column := make(matrix, len(list.Values))
+	for i, value := range list.Values {
+		column[i] = []Object{value}
+	}
+
+	return column, true, nil
+}
+
+// element_arith chains binary operations on matrix elements and keeps the
+// first error, which keeps the elimination loops below readable. Once an
+// error is recorded every further operation is a no-op.
+type element_arith struct {
+	ctx *CallContext
+	err error
+}
+
+func (a *element_arith) op(op Op, left, right Object) Object {
+	if a.err != nil {
+		return left
+	}
+
+	result, err := binary_op(a.ctx, op, left, right)
+	if err != nil {
+		a.err = err
+		return left
+	}
+
+	return result
+}
+
+// exact_context returns the context elimination runs in. Divisions in the
+// integer mode truncate and the decimal mode rounds every step to the scale,
+// so the elements are turned into exact integers and rationals and the work
+// is done with rationals, the result is truncated or rounded once at the end
+// when it is normalized.
+func exact_context(ctx *CallContext, m matrix) (*CallContext, matrix) {
+	if ctx.NumericMode != IntegerMode && ctx.NumericMode != DecimalMode {
+		return ctx, m
+	}
+
+	exact := *ctx
+	exact.NumericMode = RationalMode
+
+	result := m.clone()
+	for _, row := range result {
+		for j, value := range row {
+			switch value := value.(type) {
+			case IntObject:
+				row[j] = BigIntObject{value.Big()}
+			case DecimalObject:
+				row[j] = BigRatObject{value.Rat()}
+			}
+		}
+	}
+
+	return &exact, result
+}
+
+func is_zero_object(obj Object) bool {
+	switch value := obj.(type) {
+	case IntObject:
+		return value.Value == 0
+	case BigIntObject:
+		return value.Value.Sign() == 0
+	case BigRatObject:
+		return value.Value.Sign() == 0
+	case DecimalObject:
+		return value.Unscaled.Sign() == 0
+	case Float64Object:
+		return value.Value == 0
+	case BigFloatObject:
+		return value.Value.Sign() == 0
+	case ComplexObject:
+		return value.Value == 0
+	}
+
+	return false
+}
+
+// magnitude is the absolute value of a number as a float64, only used to
+// choose pivots so its rounding does not matter.
+func magnitude(obj Object) float64 {
+	value, err := to_complex(obj)
+	if err != nil {
+		return 0
+	}
+
+	return cmplx.Abs(value)
+}
+
+// pivot_row returns the row at or below from with the largest element in
+// column col, or -1 when all of them are negligible.
+func pivot_row(m matrix, from, col int, negligible func(Object) bool) int {
+	pivot := -1
+
+	for i := from; i < m.rows(); i++ {
+		if negligible(m[i][col]) {
+			continue
+		}
+
+		if pivot < 0 || magnitude(m[i][col]) > magnitude(m[pivot][col]) {
+			pivot = i
+		}
+	}
+
+	return pivot
+}
+
+// lu_decompose factors the square matrix m into PA = LU with partial
+// pivoting. The multipliers of L are stored below the diagonal and U on and
+// above it, perm is the row permutation P and odd its parity. A matrix
+// without a usable pivot is reported as singular.
+func lu_decompose(ctx *CallContext, m matrix) (lu matrix, perm []int, odd bool, singular bool, err error) {
+	lu = m.clone()
+	perm = make([]int, lu.rows())
+	for i := range perm {
+		perm[i] = i
+	}
+
+	arith := &element_arith{ctx: ctx}
+	for k := range lu {
+		p := pivot_row(lu, k, k, is_zero_object)
+		if p < 0 {
+			return lu, perm, odd, true, nil
+		}
+
+		if p != k {
+			lu[p], lu[k] = lu[k], lu[p]
+			perm[p], perm[k] = perm[k], perm[p]
+			odd = !odd
+		}
+
+		for i := k + 1; i < lu.rows(); i++ {
+			factor := arith.op(OpDiv, lu[i][k], lu[k][k])
+			lu[i][k] = factor
+			for j := k + 1; j < lu.cols(); j++ {
+				lu[i][j] = arith.op(OpSub, lu[i][j], arith.op(OpMul, factor, lu[k][j]))
+			}
+		}
+	}
+
+	return lu, perm, odd, false, arith.err
+}
+
+// lu_solve solves LUX = PB for every column of b with forward and back
+// substitution.
+func lu_solve(ctx *CallContext, lu matrix, perm []int, b matrix) (matrix, error) {
+	n := lu.rows()
+	arith := &element_arith{ctx: ctx}
+
+	x := make(matrix, n)
+	for i, p := range perm {
+		x[i] = slices.Clone(b[p])
+	}
+
+	for col := range b.cols() {
+		for i := range n {
+			for k := range i {
+				x[i][col] = arith.op(OpSub, x[i][col], arith.op(OpMul, lu[i][k], x[k][col]))
+			}
+		}
+
+		for i := n - 1; i >= 0; i-- {
+			for k := i + 1; k < n; k++ {
+				x[i][col] = arith.op(OpSub, x[i][col], arith.op(OpMul, lu[i][k], x[k][col]))
+			}
+			x[i][col] = arith.op(OpDiv, x[i][col], lu[i][i])
+		}
+	}
+
+	return x, arith.err
+}
+
+// determinant uses fraction free Bareiss elimination for integer matrices,
+// which stays exact in every numeric mode, and the LU decomposition for
+// anything else.
+func determinant(ctx *CallContext, m matrix) (Object, error) {
+	if ints, ok := integer_matrix(m); ok {
+		return BigIntObject{bareiss_det(ints)}, nil
+	}
+
+	ctx, m = exact_context(ctx, m)
+	lu, _, odd, singular, err := lu_decompose(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	if singular {
+		return big_int(0), nil
+	}
+
+	arith := &element_arith{ctx: ctx}
+	var result Object = big_int(1)
+	if odd {
+		result = big_int(-1)
+	}
+
+	for i := range lu {
+		result = arith.op(OpMul, result, lu[i][i])
+	}
+
+	return result, arith.err
+}
+
+func integer_matrix(m matrix) ([][]*big.Int, bool) {
+	result := make([][]*big.Int, m.rows())
+
+	for i, row := range m {
+		result[i] = make([]*big.Int, len(row))
+		for j, value := range row {
+			n, ok := exact_int(value)
+			if !ok {
+				return nil, false
+			}
+			result[i][j] = new(big.Int).Set(n)
+		}
+	}
+
+	return result, true
+}
+
+// bareiss_det computes the determinant of an integer matrix in place, every
+// division in the elimination is exact.
+func bareiss_det(m [][]*big.Int) *big.Int {
+	n := len(m)
+	sign := 1
+	prev := big.NewInt(1)
+
+	for k := 0; k < n-1; k++ {
+		if m[k][k].Sign() == 0 {
+			swap := -1
+			for i := k + 1; i < n; i++ {
+				if m[i][k].Sign() != 0 {
+					swap = i
+					break
+				}
+			}
+
+			if swap < 0 {
+				return big.NewInt(0)
+			}
+
+			m[k], m[swap] = m[swap], m[k]
+			sign = -sign
+		}
+
+		for i := k + 1; i < n; i++ {
+			for j := k + 1; j < n; j++ {
+				value := new(big.Int).Mul(m[i][j], m[k][k])
+				value.Sub(value, new(big.Int).Mul(m[i][k], m[k][j]))
+				m[i][j] = value.Quo(value, prev)
+			}
+		}
+
+		prev = m[k][k]
+	}
+
+	result := new(big.Int).Set(m[n-1][n-1])
+	if sign < 0 {
+		result.Neg(result)
+	}
+
+	return result
+}
+
+// solve_system solves AX = B for a square matrix A. b is either a plain list
+// or a matrix with as many rows as A, the solution has the same shape.
+func solve_system(ctx *CallContext, name string, a matrix, b Object) (Object, error) {
+	list, ok := b.(ListObject)
+	if !ok {
+		return nil, DomainError{Name: name, Reason: fmt.Sprintf("expects a list or a matrix but got %s", b)}
+	}
+
+	rhs, vector, err := matrix_operand(list, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if rhs.rows() != a.rows() {
+		return nil, DimensionError{Reason: fmt.Sprintf("function '%s' expects a right hand side of %d rows but got %d", name, a.rows(), rhs.rows())}
+	}
+
+	x, err := invert_into(ctx, name, a, rhs)
+	if err != nil {
+		return nil, err
+	}
+
+	if vector {
+		column := make([]Object, x.rows())
+		for i, row := range x {
+			column[i] = row[0]
+		}
+		return ListObject{column}, nil
+	}
+
+	return x.object(), nil
+}
+
+// invert_into returns the solution X of AX = B.
+func invert_into(ctx *CallContext, name string, a, b matrix) (matrix, error) {
+	_, b = exact_context(ctx, b)
+	ctx, a = exact_context(ctx, a)
+
+	lu, perm, _, singular, err := lu_decompose(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+
+	if singular {
+		return nil, DomainError{Name: name, Reason: "expects an invertible matrix but got a singular one"}
+	}
+
+	return lu_solve(ctx, lu, perm, b)
+}
+
+// matrix_rank counts the pivots of the row echelon form. Matrices of inexact
+// numbers treat elements within rounding distance of zero as zero.
+func matrix_rank(ctx *CallContext, m matrix) (Object, error) {
+	ctx, m = exact_context(ctx, m.clone())
+	negligible := rank_tolerance(ctx, m)
+
+	arith := &element_arith{ctx: ctx}
+	rank := 0
+	for col := 0; col < m.cols() && rank < m.rows(); col++ {
+		p := pivot_row(m, rank, col, negligible)
+		if p < 0 {
+			continue
+		}
+		m[p], m[rank] = m[rank], m[p]
+
+		for i := rank + 1; i < m.rows(); i++ {
+			factor := arith.op(OpDiv, m[i][col], m[rank][col])
+			for j := col; j < m.cols(); j++ {
+				m[i][j] = arith.op(OpSub, m[i][j], arith.op(OpMul, factor, m[rank][j]))
+			}
+		}
+		rank++
+	}
+
+	if arith.err != nil {
+		return nil, arith.err
+	}
+
+	return big_int(int64(rank)), nil
+}
+
+// rank_tolerance returns the test for negligible elements, exact zero for
+// exact numbers and the usual max(rows, cols) * eps * max|a| otherwise.
+func rank_tolerance(ctx *CallContext, m matrix) func(Object) bool {
+	inexact := false
+	largest := 0.0
+
+	for _, row := range m {
+		for _, value := range row {
+			switch value.(type) {
+			case Float64Object, BigFloatObject, ComplexObject:
+				inexact = true
+			}
+			largest = max(largest, magnitude(value))
+		}
+	}
+
+	if !inexact {
+		return is_zero_object
+	}
+
+	eps := math.Pow(2, -52)
+	if ctx.NumericMode == BigMode {
+		eps = math.Pow(2, 1-float64(ctx.Precision))
+	}
+	tolerance := float64(max(m.rows(), m.cols())) * eps * largest
+
+	return func(value Object) bool {
+		return is_zero_object(value) || magnitude(value) <= tolerance
+	}
+}
+
+// max_jacobi_sweeps bounds the rotations of symmetric_eigenvalues, the
+// method converges quadratically and needs far fewer in practice.
+const max_jacobi_sweeps = 100
+
+// symmetric_eigenvalues computes the eigenvalues of a real symmetric matrix
+// in ascending order with cyclic Jacobi rotations, in float64 precision.
+func symmetric_eigenvalues(ctx *CallContext, name string, m matrix) (Object, error) {
+	n := m.rows()
+	a := make([][]float64, n)
+
+	for i, row := range m {
+		a[i] = make([]float64, n)
+		for j, value := range row {
+			if j < i {
+				order, err := compare_objects(ctx, value, m[j][i])
+				if err != nil || order != 0 {
+					return nil, DomainError{Name: name, Reason: "expects a real symmetric matrix"}
+				}
+			}
+
+			f, err := to_float64(value)
+			if err != nil {
+				return nil, DomainError{Name: name, Reason: "expects a real symmetric matrix"}
+			}
+			a[i][j] = f.Value
+		}
+	}
+
+	for sweep := 0; sweep < max_jacobi_sweeps; sweep++ {
+		off, total := 0.0, 0.0
+		for i := range a {
+			for j := range a[i] {
+				total += a[i][j] * a[i][j]
+				if i != j {
+					off += a[i][j] * a[i][j]
+				}
+			}
+		}
+
+		if off <= total*1e-32 {
+			break
+		}
+
+		for p := 0; p < n-1; p++ {
+			for q := p + 1; q < n; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+
+				// the rotation angle that zeroes a[p][q], with t = tan(angle)
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				tau := s / (1 + c)
+
+				// the rotation written as corrections of the old values,
+				// which loses less to rounding than applying it directly
+				a[p][p] -= t * a[p][q]
+				a[q][q] += t * a[p][q]
+				a[p][q], a[q][p] = 0, 0
+
+				for k := range a {
+					if k == p || k == q {
+						continue
+					}
+
+					kp, kq := a[k][p], a[k][q]
+					a[k][p] = kp - s*(kq+tau*kp)
+					a[k][q] = kq + s*(kp-tau*kq)
+					a[p][k], a[q][k] = a[k][p], a[k][q]
+				}
+			}
+		}
+	}
+
+	eigenvalues := make([]float64, n)
+	for i := range a {
+		eigenvalues[i] = a[i][i]
+	}
+	slices.Sort(eigenvalues)
+
+	result := make([]Object, n)
+	for i, value := range eigenvalues {
+		result[i] = Float64Object{value}
+	}
+
+	return ListObject{result}, nil
+}
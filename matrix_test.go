@@ -0,0 +1,53 @@
+package calc
+
+import "testing"
+
+func TestMatrixProduct(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "[[1, 2], [3, 4]] * [[5, 6], [7, 8]]", Expected: "[[19, 22], [43, 50]]"},
+		{Src: "[1, 2] * [[1, 0], [0, 1]]", Expected: "[1, 2]"},
+		{Src: "[[1, 2], [3, 4]] * [1, 1]", Expected: "[3, 7]"},
+		{Src: "[[1, 2, 3]] * [[1], [2], [3]]", Expected: "[[14]]"},
+		{Src: "[[1, 2], [3, 4]] * [[1, 2, 3]]", Err: "cannot multiply a 2x2 matrix by a 1x3 matrix at 1:18"},
+		{Src: "[[1, 2], [3, 4]] * [1, 2, 3]", Err: "cannot multiply a 2x2 matrix by a 3x1 matrix"},
+		// empty operands are errors rather than empty products
+		{Src: "[[]] * []", Err: "cannot multiply a 1x0 matrix by a 0x0 matrix"},
+		{Src: "[[]] * [[]]", Err: "both need at least one element"},
+		{Src: "[] * [[1]]", Err: "both need at least one element"},
+		{Src: "[[1]] * [[]]", Err: "both need at least one element"},
+		// ragged operands on either side
+		{Src: "[[1, 2], [3]] * [1, 2]", Err: "matrix rows have different lengths 2 and 1 at 1:15"},
+		{Src: "[[1, 2], [3, 4]] * [[1], [2, 3]]", Err: "matrix rows have different lengths 1 and 2"},
+	})
+}
+
+func TestLinearAlgebra(t *testing.T) {
+	rational := []Option{WithNumericMode(RationalMode)}
+	decimal := []Option{WithNumericMode(DecimalMode)}
+	integer := []Option{WithNumericMode(IntegerMode)}
+
+	check_eval_cases(t, []eval_case{
+		{Src: "det([[1, 2], [3, 4]])", Expected: "-2"},
+		{Src: "det([[2, 0, 1], [1, 3, 2], [1, 1, 2]])", Expected: "6"},
+		{Src: "det([[1/2, 1], [2, 3]])", Opts: rational, Expected: "-0.5"},
+		{Src: "det([[1/3, 1], [1, 1]]) + 2/3", Opts: rational, Expected: "0"},
+		{Src: "det([[1.5, 2], [3, 4]])", Opts: decimal, Expected: "0"},
+		{Src: "inv([[1, 2], [3, 4]])", Opts: rational, Expected: "[[-2, 1], [1.5, -0.5]]"},
+		{Src: "inv([[1, 2], [3, 4]])", Opts: decimal, Expected: "[[-2, 1], [1.5, -0.5]]"},
+		{Src: "inv([[3, 1], [1, 3]])", Opts: decimal, Expected: "[[0.38, -0.12], [-0.12, 0.38]]"},
+		{Src: "inv([[2, 0], [0, 4]])", Opts: integer, Expected: "[[0, 0], [0, 0]]"},
+		{Src: "solve([[2, 1], [1, 3]], [3, 5])", Opts: rational, Expected: "[0.8, 1.4]"},
+		{Src: "solve([[3, 1], [1, 2]], [9, 8])", Opts: decimal, Expected: "[2, 3]"},
+		{Src: "solve([[2, 0], [0, 4]], [[2, 4], [4, 8]])", Opts: rational, Expected: "[[1, 2], [1, 2]]"},
+		{Src: "transpose([[1, 2, 3], [4, 5, 6]])", Expected: "[[1, 4], [2, 5], [3, 6]]"},
+		{Src: "trace([[1, 2], [3, 4]])", Expected: "5"},
+		{Src: "rank([[1, 2], [2, 4]])", Expected: "1"},
+		{Src: "rank([[1, 2, 3], [4, 5, 6], [7, 8, 10]])", Expected: "3"},
+		{Src: "eig([[2, 1], [1, 2]])", Expected: "[1, 3]"},
+		{Src: "inv([[1, 2], [2, 4]])", Err: "expects an invertible matrix but got a singular one"},
+		{Src: "det([[1, 2, 3]])", Err: "function 'det' expects a square matrix but got a 1x3 matrix at 1:1"},
+		{Src: "det([1, 2])", Err: "function 'det' expects a matrix but got [1, 2]"},
+		{Src: "eig([[1, 2], [3, 4]])", Err: "expects a real symmetric matrix"},
+		{Src: "solve([[1, 0], [0, 1]], [1, 2, 3])", Err: "expects a right hand side of 2 rows but got 3"},
+	})
+}
@@ -0,0 +1,110 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+type BigIntObject struct {
+	Value *big.Int
+}
+
+func (obj BigIntObject) GetValue() interface{} {
+	return obj.Value
+}
+
+func (obj BigIntObject) String() string {
+	return obj.Value.String()
+}
+
+type BigRatObject struct {
+	Value *big.Rat
+}
+
+func (obj BigRatObject) GetValue() interface{} {
+	return obj.Value
+}
+
+// rat_display_prec is the precision used to print rationals that have no
+// finite decimal expansion.
+const rat_display_prec = default_precision
+
+func (obj BigRatObject) String() string {
+	if digits, exact := obj.Value.FloatPrec(); exact {
+		return obj.Value.FloatString(digits)
+	}
+
+	value := new(big.Float).SetPrec(rat_display_prec).SetRat(obj.Value)
+	return big_float_text(value, 'g', display_digits(rat_display_prec))
+}
+
+type BigFloatObject struct {
+	Value *big.Float
+}
+
+func (obj BigFloatObject) GetValue() interface{} {
+	return obj.Value
+}
+
+func (obj BigFloatObject) String() string {
+	// values converted from float64 print like float64 does, with the fewest
+	// digits that identify them
+	if obj.Value.Prec() <= 53 {
+		return big_float_text(obj.Value, 'g', -1)
+	}
+
+	return big_float_text(obj.Value, 'g', display_digits(obj.Value.Prec()))
+}
+
+// max_text_exp is the largest binary exponent big_float_text leaves to Text,
+// whose decimal conversion slows down quadratically with the exponent.
+const max_text_exp = 1 << 16
+
+// big_float_text is x.Text for the 'e' and 'g' formats. Huge and tiny x are
+// divided by a power of ten first, so only a mantissa of the precision of x
+// is converted and 2.5^100000000 prints as quickly as 2.5^100.
+func big_float_text(x *big.Float, format byte, digits int) string {
+	exp := x.MantExp(nil)
+	if x.IsInf() || x.Sign() == 0 || (exp <= max_text_exp && exp >= -max_text_exp) {
+		return x.Text(format, digits)
+	}
+
+	prec := x.Prec() + guard_bits
+	power := int64(float64(exp) * math.Log10(2))
+	ten := big_pow(new_big_float(prec).SetInt64(10), new_big_float(prec).SetInt64(power), prec)
+	scaled := new_big_float(x.Prec()).Quo(x, ten)
+
+	// the shortest digits of the scaled mantissa are not those of x, all the
+	// digits that x holds are printed instead
+	if digits < 0 {
+		digits = int(math.Ceil(float64(x.Prec())*math.Log10(2))) + 1
+	}
+
+	// 'g' counts all significant digits, 'e' only those after the point
+	if format == 'g' && digits > 0 {
+		digits--
+	}
+
+	mantissa, exponent, _ := strings.Cut(scaled.Text('e', digits), "e")
+	n, _ := strconv.ParseInt(exponent, 10, 64)
+
+	if format == 'g' && strings.Contains(mantissa, ".") {
+		mantissa = strings.TrimRight(strings.TrimRight(mantissa, "0"), ".")
+	}
+
+	return fmt.Sprintf("%se%+03d", mantissa, n+power)
+}
+
+// display_digits is the number of significant decimal digits worth printing
+// for a mantissa of prec bits. The last couple of digits are held back since
+// they carry the rounding error of the binary representation.
+func display_digits(prec uint) int {
+	return max(int(float64(prec)*math.Log10(2))-2, 1)
+}
+
+func big_int(value int64) BigIntObject {
+	return BigIntObject{big.NewInt(value)}
+}
@@ -1,4 +1,4 @@
-package main
+package calc
 
 import (
 	"fmt"
@@ -19,20 +19,50 @@ const (
 	OpMod
 	OpPow
 	OpExit
+	OpAnd
+	OpOr
+	OpXor
+	OpShl
+	OpShr
+	OpIntDiv
+	OpNot
+	OpList
+	OpIndex
+	OpConvert
+	OpPercent
+	OpAddPercent
+	OpSubPercent
+	OpPercentOf
+	OpLoad
 )
 
 var op_map = map[Op]string{
-	OpNoop:     "Noop",
-	OpConstant: "Constant",
-	OpCall:     "Call",
-	OpPop:      "Pop",
-	OpAdd:      "Add",
-	OpSub:      "Sub",
-	OpMul:      "Mul",
-	OpDiv:      "Div",
-	OpMod:      "Mod",
-	OpPow:      "Pow",
-	OpExit:     "Exit",
+	OpNoop:       "Noop",
+	OpConstant:   "Constant",
+	OpCall:       "Call",
+	OpPop:        "Pop",
+	OpAdd:        "Add",
+	OpSub:        "Sub",
+	OpMul:        "Mul",
+	OpDiv:        "Div",
+	OpMod:        "Mod",
+	OpPow:        "Pow",
+	OpExit:       "Exit",
+	OpAnd:        "And",
+	OpOr:         "Or",
+	OpXor:        "Xor",
+	OpShl:        "Shl",
+	OpShr:        "Shr",
+	OpIntDiv:     "IntDiv",
+	OpNot:        "Not",
+	OpList:       "List",
+	OpIndex:      "Index",
+	OpConvert:    "Convert",
+	OpPercent:    "Percent",
+	OpAddPercent: "AddPercent",
+	OpSubPercent: "SubPercent",
+	OpPercentOf:  "PercentOf",
+	OpLoad:       "Load",
 }
 
 func (op Op) String() string {
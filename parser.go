@@ -1,36 +1,107 @@
-package main
+package calc
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"path"
 	"slices"
 	"strconv"
 	"strings"
 )
 
+// ImplicitPrecedence selects how tightly implicit multiplication like 2pi or
+// 3(4+5) binds.
+type ImplicitPrecedence byte
+
+const (
+	// ImplicitLoose gives implicit multiplication the precedence of *, so
+	// 1/2pi is (1/2)*pi.
+	ImplicitLoose ImplicitPrecedence = iota
+	// ImplicitTight binds implicit multiplication tighter than the operators
+	// of the term level like * and /, so 1/2pi is 1/(2*pi).
+	ImplicitTight
+)
+
+var implicit_precedence_map = map[ImplicitPrecedence]string{
+	ImplicitLoose: "same",
+	ImplicitTight: "tight",
+}
+
+func (precedence ImplicitPrecedence) String() string {
+	if name, ok := implicit_precedence_map[precedence]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("%d", precedence)
+}
+
+func ParseImplicitPrecedence(name string) (ImplicitPrecedence, error) {
+	for precedence, precedence_name := range implicit_precedence_map {
+		if precedence_name == name {
+			return precedence, nil
+		}
+	}
+
+	return ImplicitLoose, fmt.Errorf("unknown implicit multiplication precedence '%s'", name)
+}
+
 type Parser struct {
 	filepath string
 	filename string
 	input    []byte
-	lexer    *Lexer
+	tokens   *TokenStream
+	implicit ImplicitPrecedence
+	locale   Locale
+	// numeric_mode tells whether number literals have to fit a float64
+	numeric_mode NumericMode
+	// separator separates arguments and list items, a semicolon in locales
+	// with a decimal comma
+	separator token_type
+	// variables are the names never read as units after a number, the
+	// declared parameters and the names used as operands, so 2t + t is 3t
+	// and not two tonnes plus t
+	variables map[string]bool
+	// operands are the names read as constants or parameters
+	operands map[string]bool
 }
 
-func NewParser(input []byte, filepath string) Parser {
+func NewParser(input []byte, filepath string, opts ...Option) Parser {
+	config := NewConfig(opts...)
+
+	variables := map[string]bool{}
+	for _, name := range config.Params {
+		variables[name] = true
+	}
+
 	return Parser{
 		filepath: filepath,
 		filename: path.Base(filepath),
 		input:    input,
+		implicit: config.Implicit,
+		locale:   config.Locale,
+
+		numeric_mode: config.NumericMode,
+		variables:    variables,
 	}
 }
 
 func (p Parser) expect(expected []token_type) (Token, error) {
-	token := p.lexer.Next()
+	token := p.tokens.Next()
 
 	expected_str := []string{}
 	for _, e := range expected {
 		expected_str = append(expected_str, token_map[e])
 	}
 
+	switch token.TokenType {
+	case InvalidNumberToken:
+		return Illegal, fmt.Errorf("malformed number literal '%s' at %d:%d", token.Literal, token.Location.Line, token.Location.Col)
+	case UnterminatedCommentToken:
+		return Illegal, fmt.Errorf("unterminated block comment at %d:%d", token.Location.Line, token.Location.Col)
+	}
+
 	if !slices.Contains(expected, token.TokenType) {
 		if len(expected_str) == 1 {
 			return Illegal, fmt.Errorf("invalid '%s' token, was expecting a '%s' token at %d:%d", token, expected_str[0], token.Location.Line, token.Location.Col)
@@ -43,118 +114,492 @@ func (p Parser) expect(expected []token_type) (Token, error) {
 }
 
 func (p *Parser) Parse() (Expr, error) {
-	p.lexer = NewLexer(p.input)
-	return p.parse_expr(false)
+	expr, err := p.parse()
+
+	// parse again when a unit name turned out to be used as an operand
+	retry := false
+	for name := range p.operands {
+		if _, ok := lookup_unit(name); ok && !p.variables[name] {
+			p.variables[name] = true
+			retry = true
+		}
+	}
+	if retry {
+		return p.parse()
+	}
+
+	return expr, err
+}
+
+func (p *Parser) parse() (Expr, error) {
+	p.tokens = NewTokenStream(p.input, p.locale)
+	p.operands = map[string]bool{}
+	p.separator = CommaToken
+	if p.locale.Separator == ';' {
+		p.separator = SemicolonToken
+	}
+
+	expr, err := p.parse_program()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.expect([]token_type{PlusToken, MinusToken, StarToken, ForwardSlashToken, DoubleSlashToken, PercentToken, CaretToken, BangToken, AmpersandToken, PipeToken, XorToken, ShiftLeftToken, ShiftRightToken, ToToken, InToken, EOFToken})
+	if err != nil {
+		return nil, err
+	}
+
+	return expr, nil
 }
 
 /*
 "Arithmetic Expressions" {
-expression = term  { ("+" | "-") term} .
-term       = factor  { ("*"|"/") factor} .
-factor     = constant | variable | "("  expression  ")"  | fn.
+program    = [ "mode" angle_mode [ ";" ] ] expression .
+angle_mode = "rad" | "deg" | "grad" .
+expression = bit_or { ("to" | "in") unit } .
+bit_or     = bit_xor { "|" bit_xor } .
+bit_xor    = bit_and { "xor" bit_and } .
+bit_and    = shift { "&" shift } .
+shift      = sum { ("<<" | ">>") sum } .
+sum        = term  { ("+" | "-") term} .
+term       = percent { ("*"|"/"|"//"|"%") percent | implicit_operand } .
+percent    = implicit [ "%" [ ("of" | "off") percent ] ] .
+implicit   = unary { implicit_operand } .
+unary      = ("~" | "√") unary | power .
+power      = postfix [ "^" unary ] .
+postfix    = factor { "!" | "[" expression "]" | superscript } .
+factor     = quantity | imaginary | variable | "("  expression  ")"  | list | fn.
+quantity   = constant [ unit ] .
+unit       = unit_power { ("*" | "/") unit_power } .
+unit_power = unit_name [ "^" integer | superscript ] .
+list       = "[" [ arg_list ] "]" .
 fn = variable "(" arg_list ")"
+implicit_operand = unary .
 arg_list = expression | expression "," arg_list
 variable   = "x" | "y" | "z" .
-constant   = digit {digit} .
-digit      = "0" | "1" | "..." | "9" .
+constant   = number .
+imaginary  = number "i" .
+number     = see lex_number .
+superscript = { "⁰" … "⁹" | "⁻" } .
 }
+
+An implicit_operand is a unary starting with a name, a "(" or a "√" that
+directly follows another operand, as in 2pi, 3(4+5) or (a+b)(a-b). It
+multiplies at the term level by default and at the implicit level when
+ImplicitTight is selected, the other of the two repetitions is not taken.
+A name followed by "(" is always a function call, sin(x) is not sin*x.
+Powers bind tighter than either, 2x^2 is 2*(x^2) like 2x² is.
+
+A % is the modulo operator when an operand follows it and a percentage
+otherwise. A percentage on its own is a hundredth, 10% is 0.1, a sum with a
+percentage on its right adds that share of its left, 200 + 10% is 220, p% of
+y takes the share of y and x% off y takes it away from y. Only a percentage
+written right at the sum is a share, 200 + (10%) is 200.1.
+
+A unit name right after a number makes a quantity, 5 km. The in after a
+number is the inch unless a unit follows it, so 12 in to cm converts 12
+inches and 5 ft in cm converts 5 feet. Names used as operands anywhere in
+the program and the parameters declared with WithParams are never units
+after a number, 2t + t is 3t.
+
+Line comments start with #, block comments are enclosed in slash star and
+star slash like in C. A double slash starts a line comment too where it
+cannot divide, at the start of a line or after an operator or an opening
+bracket. Right after an operand on the same line it is integer division,
+7 // 2 is 3, so comments after an expression start with #.
 */
 
-func (p *Parser) parse_expr(inline bool) (Expr, error) {
-	term, err := p.parse_term()
+// binary_level parses one precedence level of left associative binary
+// operators, with operands parsed by the next tighter level.
+func (p *Parser) binary_level(operand func() (Expr, error), ops map[token_type]OpType) (Expr, error) {
+	left, err := operand()
 	if err != nil {
 		return nil, err
 	}
 
-	parse_right := func(op_type OpType) (BinaryExpr, error) {
-		right, err := p.parse_term()
+	for {
+		token := p.tokens.Peek(0)
+		op_type, ok := ops[token.TokenType]
+		if !ok {
+			return left, nil
+		}
+		p.tokens.Next()
+
+		right, err := operand()
 		if err != nil {
-			return BinaryExpr{}, err
+			return nil, err
 		}
 
-		if !inline {
-			_, err = p.expect([]token_type{PlusToken, MinusToken, StarToken, ForwardSlashToken, PercentToken, CaretToken, EOFToken})
+		left = binary_expr(left, right, op_type, token.Location)
+	}
+}
+
+// parse_program parses the mode statement a program may start with, the mode
+// applies to the whole program. mode is only a statement when an angle mode
+// follows it, so the mode() builtin can still be called.
+func (p *Parser) parse_program() (Expr, error) {
+	keyword, name := p.tokens.Peek(0), p.tokens.Peek(1)
+	if keyword.TokenType != IdentifierToken || keyword.Literal != "mode" || name.TokenType != IdentifierToken {
+		return p.parse_expr()
+	}
+
+	mode, err := ParseAngleMode(name.Literal)
+	if err != nil {
+		return p.parse_expr()
+	}
+	p.tokens.Skip(2)
+
+	if p.tokens.Peek(0).TokenType == SemicolonToken {
+		p.tokens.Next()
+	}
+
+	expr, err := p.parse_expr()
+	if err != nil {
+		return nil, err
+	}
+
+	return angle_mode_expr(mode, expr), nil
+}
+
+func (p *Parser) parse_expr() (Expr, error) {
+	expr, err := p.parse_bit_or()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		token := p.tokens.Peek(0)
+		if token.TokenType != ToToken && token.TokenType != InToken {
+			return expr, nil
+		}
+		p.tokens.Next()
+
+		unit, err := p.parse_unit()
+		if err != nil {
+			return nil, err
 		}
 
-		return binary_expr(term, right, op_type), err
+		expr = convert_expr(expr, unit, token.Location)
 	}
+}
 
-	next := p.lexer.Next()
+func (p *Parser) parse_bit_or() (Expr, error) {
+	return p.binary_level(p.parse_bit_xor, map[token_type]OpType{PipeToken: OpTypeOr})
+}
 
-	switch next.TokenType {
-	case EOFToken:
-		return term, nil
-	case PlusToken:
-		return parse_right(OpTypeAdd)
-	case MinusToken:
-		return parse_right(OpTypeSub)
-	default:
-		p.lexer.Prev()
+func (p *Parser) parse_bit_xor() (Expr, error) {
+	return p.binary_level(p.parse_bit_and, map[token_type]OpType{XorToken: OpTypeXor})
+}
 
-		if !inline {
-			_, err = p.expect([]token_type{PlusToken, MinusToken, StarToken, ForwardSlashToken, PercentToken, CaretToken})
+func (p *Parser) parse_bit_and() (Expr, error) {
+	return p.binary_level(p.parse_shift, map[token_type]OpType{AmpersandToken: OpTypeAnd})
+}
+
+func (p *Parser) parse_shift() (Expr, error) {
+	return p.binary_level(p.parse_sum, map[token_type]OpType{ShiftLeftToken: OpTypeShl, ShiftRightToken: OpTypeShr})
+}
+
+func (p *Parser) parse_sum() (Expr, error) {
+	term, err := p.parse_term()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		var op_type OpType
+
+		token := p.tokens.Peek(0)
+		switch token.TokenType {
+		case PlusToken:
+			op_type = OpTypeAdd
+		case MinusToken:
+			op_type = OpTypeSub
+		default:
+			return term, nil
+		}
+		p.tokens.Next()
+
+		right, err := p.parse_term()
+		if err != nil {
 			return nil, err
 		}
 
-		return term, nil
+		if _, ok := right.(PercentExpr); ok {
+			op_type = percent_op_types[op_type]
+		}
+
+		term = binary_expr(term, right, op_type, token.Location)
 	}
 }
 
+// percent_op_types are the sums with a percentage on their right.
+var percent_op_types = map[OpType]OpType{
+	OpTypeAdd: OpTypeAddPercent,
+	OpTypeSub: OpTypeSubPercent,
+}
+
 func (p *Parser) parse_term() (Expr, error) {
-	factor, err := p.parse_factor()
+	factor, err := p.parse_percent()
 	if err != nil {
 		return nil, err
 	}
 
-	parse_right := func(op_type OpType) (BinaryExpr, error) {
-		right, err := p.parse_factor()
+	for {
+		var op_type OpType
+
+		token := p.tokens.Peek(0)
+		switch token.TokenType {
+		case StarToken:
+			op_type = OpTypeMul
+		case ForwardSlashToken:
+			op_type = OpTypeDiv
+		case DoubleSlashToken:
+			op_type = OpTypeIntDiv
+		case PercentToken:
+			op_type = OpTypeMod
+		default:
+			if p.implicit == ImplicitLoose && starts_implicit_operand(token) {
+				right, err := p.percent_of(p.parse_unary())
+				if err != nil {
+					return nil, err
+				}
+
+				factor = binary_expr(factor, right, OpTypeMul, token.Location)
+				continue
+			}
+			return factor, nil
+		}
+		p.tokens.Next()
+
+		right, err := p.parse_percent()
 		if err != nil {
-			return BinaryExpr{}, err
+			return nil, err
 		}
 
-		return binary_expr(factor, right, op_type), nil
+		factor = binary_expr(factor, right, op_type, token.Location)
 	}
+}
 
-	next := p.lexer.Next()
+func (p *Parser) parse_percent() (Expr, error) {
+	return p.percent_of(p.parse_implicit())
+}
 
+// percent_of reads the % after an operand as a percentage when no operand
+// follows it, a % followed by an operand is left for the term level to read
+// as modulo.
+func (p *Parser) percent_of(operand Expr, err error) (Expr, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	token := p.tokens.Peek(0)
+	if token.TokenType != PercentToken || starts_operand(p.tokens.Peek(1)) {
+		return operand, nil
+	}
+	p.tokens.Next()
+
+	percent := percent_expr(operand, token.Location)
+
+	var op_type OpType
+	next := p.tokens.Peek(0)
 	switch next.TokenType {
-	case EOFToken:
-		return factor, nil
-	case StarToken:
-		return parse_right(OpTypeMul)
-	case ForwardSlashToken:
-		return parse_right(OpTypeDiv)
-	case PercentToken:
-		return parse_right(OpTypeMod)
-	case CaretToken:
-		return parse_right(OpTypePow)
+	case OfToken:
+		op_type = OpTypeOf
+	case OffToken:
+		op_type = OpTypeOff
 	default:
-		p.lexer.Prev()
-		return factor, nil
+		return percent, nil
+	}
+	p.tokens.Next()
+
+	right, err := p.parse_percent()
+	if err != nil {
+		return nil, err
+	}
+
+	return binary_expr(percent, right, op_type, next.Location), nil
+}
+
+// parse_implicit parses the implicit multiplications that bind tighter than
+// * with ImplicitTight, with ImplicitLoose the term level takes them.
+func (p *Parser) parse_implicit() (Expr, error) {
+	factor, err := p.parse_unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.implicit == ImplicitTight && starts_implicit_operand(p.tokens.Peek(0)) {
+		token := p.tokens.Peek(0)
+
+		right, err := p.parse_unary()
+		if err != nil {
+			return nil, err
+		}
+
+		factor = binary_expr(factor, right, OpTypeMul, token.Location)
+	}
+
+	return factor, nil
+}
+
+// starts_operand reports whether a token can start an operand.
+func starts_operand(token Token) bool {
+	switch token.TokenType {
+	case NumberToken, OpenBracketToken, TildeToken:
+		return true
+	}
+
+	return starts_implicit_operand(token)
+}
+
+// starts_implicit_operand reports whether a token right after an operand
+// starts another operand it is multiplied with. Numbers do not, 2 3 is
+// rather a typo than a product.
+func starts_implicit_operand(token Token) bool {
+	switch token.TokenType {
+	case IdentifierToken, OpenParensToken, RootToken:
+		return true
+	}
+
+	return false
+}
+
+func (p *Parser) parse_unary() (Expr, error) {
+	token := p.tokens.Peek(0)
+	switch token.TokenType {
+	case TildeToken:
+		p.tokens.Next()
+		expr, err := p.parse_unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return unary_expr(expr, OpTypeNot), nil
+	case RootToken:
+		p.tokens.Next()
+		expr, err := p.parse_unary()
+		if err != nil {
+			return nil, err
+		}
+
+		call := fn_call("sqrt", expr)
+		call.Location = token.Location
+		return call, nil
+	}
+
+	return p.parse_power()
+}
+
+// parse_power parses the right associative powers, 2^3^2 is 2^(3^2). The
+// exponent is a unary so 2^√4 works, negative exponents are number literals.
+// A negative number literal as the base only lends its sign to the power,
+// -2^2 is -(2^2) like in written math.
+func (p *Parser) parse_power() (Expr, error) {
+	base, err := p.parse_postfix()
+	if err != nil {
+		return nil, err
+	}
+
+	token := p.tokens.Peek(0)
+	if token.TokenType != CaretToken {
+		return base, nil
+	}
+	p.tokens.Next()
+
+	exponent, err := p.parse_unary()
+	if err != nil {
+		return nil, err
+	}
+
+	if literal, ok := base.(FloatLiteralExpr); ok && strings.HasPrefix(literal.Literal, "-") {
+		magnitude := f_literal(-literal.Value, literal.Literal[1:])
+		power := binary_expr(magnitude, exponent, OpTypePow, token.Location)
+		return binary_expr(f_literal(0, "0"), power, OpTypeSub, token.Location), nil
+	}
+
+	return binary_expr(base, exponent, OpTypePow, token.Location), nil
+}
+
+func (p *Parser) parse_postfix() (Expr, error) {
+	factor, err := p.parse_factor()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		token := p.tokens.Peek(0)
+		switch token.TokenType {
+		case BangToken:
+			p.tokens.Next()
+			call := fn_call("fact", factor)
+			call.Location = token.Location
+			factor = call
+		case OpenBracketToken:
+			p.tokens.Next()
+			index, err := p.parse_expr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect([]token_type{CloseBracketToken}); err != nil {
+				return nil, err
+			}
+			factor = index_expr(factor, index)
+		case SuperscriptToken:
+			p.tokens.Next()
+			power, err := superscript_value(token.Literal)
+			if err != nil {
+				return nil, fmt.Errorf("malformed exponent '%s' at %d:%d", token.Literal, token.Location.Line, token.Location.Col)
+			}
+			exponent := f_literal(float64(power), strconv.Itoa(power))
+			factor = binary_expr(factor, exponent, OpTypePow, token.Location)
+		default:
+			return factor, nil
+		}
 	}
 }
 
 func (p *Parser) parse_factor() (Expr, error) {
-	token, err := p.expect([]token_type{NumberToken, IdentifierToken, OpenParensToken})
+	token, err := p.expect([]token_type{NumberToken, IdentifierToken, OpenParensToken, OpenBracketToken})
 	if err != nil {
 		return nil, err
 	}
 
 	switch token.TokenType {
 	case NumberToken:
-		value, _ := strconv.ParseFloat(token.Literal, 64)
-		return f_literal(value), nil
-	case IdentifierToken:
-		next := p.lexer.Next()
+		source, imaginary := strings.CutSuffix(token.Literal, "i")
+		canonical := canonical_number(source)
+
+		value, err := number_value(canonical)
+		if errors.Is(err, strconv.ErrRange) {
+			// the other modes read real literals exactly from their text
+			if imaginary || p.numeric_mode == FloatMode {
+				return nil, fmt.Errorf("number literal '%s' is out of range at %d:%d", token.Literal, token.Location.Line, token.Location.Col)
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("malformed number literal '%s' at %d:%d", token.Literal, token.Location.Line, token.Location.Col)
+		}
 
-		if next.TokenType == OpenParensToken {
-			return p.parse_call_expr(token.Literal)
-		} else {
-			p.lexer.Prev()
-			return c_literal(token.Literal), nil
+		if imaginary {
+			return i_literal(value, canonical+"i"), nil
+		}
+
+		literal := f_literal(value, canonical)
+		if unit, ok := p.parse_unit_suffix(); ok {
+			return quantity_expr(literal, unit), nil
+		}
+		if next := p.tokens.Peek(0); offset_units[next.Literal] && next.TokenType == IdentifierToken {
+			return nil, offset_unit_err(next)
 		}
+		return literal, nil
+	case IdentifierToken:
+		if p.tokens.Peek(0).TokenType == OpenParensToken {
+			p.tokens.Next()
+			return p.parse_call_expr(token)
+		}
+		p.operands[token.Literal] = true
+		return c_literal(token.Literal), nil
 	case OpenParensToken:
-		expr, err := p.parse_expr(true)
+		expr, err := p.parse_expr()
 		if err != nil {
 			return nil, err
 		}
@@ -164,18 +609,152 @@ func (p *Parser) parse_factor() (Expr, error) {
 		}
 
 		return group_expr(expr), nil
+	case OpenBracketToken:
+		return p.parse_list_expr()
 	}
 
 	return nil, nil
 }
 
-func (p *Parser) parse_call_expr(name string) (FnCallExpr, error) {
-	next := p.lexer.Next()
-	if next.TokenType == CloseParensToken {
-		return fn_call(name), nil
+// parse_unit_suffix parses the unit after a number literal. Names that are
+// not units or are called as functions are left for the parser to read, as
+// are the operators that do not continue the unit. An in is the inch unless
+// a unit follows it, 12 in to cm converts 12 inches while 12 in cm converts
+// the plain number 12. Variables are not units, with a parameter t 2t is
+// 2*t.
+func (p *Parser) parse_unit_suffix() (Unit, bool) {
+	if p.tokens.Peek(0).TokenType == InToken {
+		if _, _, ok := p.peek_unit_power(1, p.variables); ok {
+			return Unit{}, false
+		}
+	}
+
+	return p.parse_unit_terms(p.variables)
+}
+
+// parse_unit_terms parses a product of unit powers, names in skip are not
+// read as units.
+func (p *Parser) parse_unit_terms(skip map[string]bool) (Unit, bool) {
+	unit, size, ok := p.peek_unit_power(0, skip)
+	if !ok {
+		return Unit{}, false
+	}
+	p.tokens.Skip(size)
+
+	for {
+		sign := 0
+		switch p.tokens.Peek(0).TokenType {
+		case StarToken:
+			sign = 1
+		case ForwardSlashToken:
+			sign = -1
+		}
+
+		next, size, ok := p.peek_unit_power(1, skip)
+		if sign == 0 || !ok {
+			return unit, true
+		}
+		p.tokens.Skip(1 + size)
+
+		unit = unit.mul(next, sign)
+	}
+}
+
+// peek_unit_power looks for a unit name with an optional integer power at the
+// nth token ahead, it returns the unit and how many tokens it spans.
+func (p *Parser) peek_unit_power(n int, skip map[string]bool) (Unit, int, bool) {
+	name := p.tokens.Peek(n)
+	if (name.TokenType != IdentifierToken && name.TokenType != InToken) || skip[name.Literal] {
+		return Unit{}, 0, false
+	}
+
+	if _, ok := lookup_unit(name.Literal); !ok || p.tokens.Peek(n+1).TokenType == OpenParensToken {
+		return Unit{}, 0, false
+	}
+
+	switch power := p.tokens.Peek(n + 1); power.TokenType {
+	case CaretToken:
+		power := p.tokens.Peek(n + 2)
+		if n, err := strconv.Atoi(power.Literal); power.TokenType == NumberToken && err == nil && n != 0 {
+			return unit_of(name.Literal, n), 3, true
+		}
+	case SuperscriptToken:
+		if n, err := superscript_value(power.Literal); err == nil && n != 0 {
+			return unit_of(name.Literal, n), 2, true
+		}
+	}
+
+	return unit_of(name.Literal, 1), 1, true
+}
+
+// parse_unit parses the unit a value is converted to, which unlike a unit
+// suffix has to be there. Names of variables are units here, x to m converts
+// even when m is a parameter elsewhere.
+func (p *Parser) parse_unit() (Unit, error) {
+	token := p.tokens.Peek(0)
+
+	unit, ok := p.parse_unit_terms(nil)
+	if !ok {
+		if offset_units[token.Literal] && token.TokenType == IdentifierToken {
+			return Unit{}, offset_unit_err(token)
+		}
+		if token.TokenType == IdentifierToken {
+			return Unit{}, fmt.Errorf("unknown unit '%s' at %d:%d", token.Literal, token.Location.Line, token.Location.Col)
+		}
+		return Unit{}, fmt.Errorf("invalid '%s' token, was expecting a unit at %d:%d", token, token.Location.Line, token.Location.Col)
+	}
+
+	return unit, nil
+}
+
+// canonical_number rewrites a number literal into the form the numeric
+// modes parse, without digit separators, with an ascii minus and with the
+// engineering suffix turned into an exponent.
+func canonical_number(literal string) string {
+	literal = strings.ReplaceAll(literal, "_", "")
+	literal = strings.ReplaceAll(literal, "−", "-")
+	if is_based_literal(literal) {
+		return literal
+	}
+
+	for suffix, exponent := range si_suffixes {
+		if digits, ok := strings.CutSuffix(literal, string(suffix)); ok {
+			return fmt.Sprintf("%se%d", digits, exponent)
+		}
+	}
+
+	return literal
+}
+
+// number_value returns the float value of a canonical number literal, 0x, 0b
+// and 0o literals are read as integers. Literals too large for a float64 come
+// back as an infinity along with strconv.ErrRange, the exact numeric modes
+// parse them again from their text.
+func number_value(literal string) (float64, error) {
+	if is_based_literal(literal) {
+		value, ok := new(big.Int).SetString(literal, 0)
+		if !ok {
+			return 0, fmt.Errorf("invalid number literal '%s'", literal)
+		}
+		result, _ := new(big.Float).SetInt(value).Float64()
+		if math.IsInf(result, 0) {
+			return result, strconv.ErrRange
+		}
+		return result, nil
+	}
+
+	return strconv.ParseFloat(literal, 64)
+}
+
+func (p *Parser) parse_call_expr(name Token) (FnCallExpr, error) {
+	call := fn_call(name.Literal)
+	call.Location = name.Location
+
+	if p.tokens.Peek(0).TokenType == CloseParensToken {
+		p.tokens.Next()
+		return call, nil
 	}
 
-	p.lexer.Prev()
 	args, err := p.parse_arg_list()
 	if err != nil {
 		return FnCallExpr{}, err
@@ -186,27 +765,45 @@ func (p *Parser) parse_call_expr(name string) (FnCallExpr, error) {
 		return FnCallExpr{}, err
 	}
 
-	return fn_call(name, args...), nil
+	call.Args = args
+	return call, nil
+}
+
+func (p *Parser) parse_list_expr() (ListExpr, error) {
+	if p.tokens.Peek(0).TokenType == CloseBracketToken {
+		p.tokens.Next()
+		return list_expr(), nil
+	}
+
+	items, err := p.parse_arg_list()
+	if err != nil {
+		return ListExpr{}, err
+	}
+
+	_, err = p.expect([]token_type{CloseBracketToken})
+	if err != nil {
+		return ListExpr{}, err
+	}
+
+	return list_expr(items...), nil
 }
 
 func (p *Parser) parse_arg_list() ([]Expr, error) {
-	left, err := p.parse_expr(true)
+	left, err := p.parse_expr()
 	if err != nil {
 		return nil, err
 	}
 
 	result := []Expr{left}
-	next := p.lexer.Next()
 
-	if next.TokenType == CommaToken {
+	if p.tokens.Peek(0).TokenType == p.separator {
+		p.tokens.Next()
 		right, err := p.parse_arg_list()
 		if err != nil {
 			return nil, err
 		}
 		result = append(result, right...)
-		return result, nil
-	} else {
-		p.lexer.Prev()
-		return result, nil
 	}
+
+	return result, nil
 }
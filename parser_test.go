@@ -0,0 +1,128 @@
+package calc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPowerPrecedence(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "2*3^2", Expected: "18"},
+		{Src: "8/2^2", Expected: "2"},
+		{Src: "2^3*2", Expected: "16"},
+		{Src: "2^3^2", Expected: "512"},
+		{Src: "2^-1", Expected: "0.5"},
+		{Src: "2^-2^2", Expected: "0.0625"},
+		{Src: "-2^2", Expected: "-4"},
+		{Src: "-2^3", Opts: []Option{WithNumericMode(IntegerMode)}, Expected: "-8"},
+		{Src: "(-2)^2", Expected: "4"},
+		{Src: "1 - 2^2", Expected: "-3"},
+		{Src: "2^3!", Expected: "64"},
+		{Src: "3!^2", Expected: "36"},
+		{Src: "10 % 3^2", Expected: "1"},
+		{Src: "2^(1+1)", Expected: "4"},
+	})
+}
+
+func TestImplicitMultiplication(t *testing.T) {
+	bindings := map[string]float64{"x": 3, "r": 0.5, "a": 4, "b": 1}
+
+	cases := []struct {
+		src   string
+		loose string
+		tight string
+	}{
+		{"2x", "6", "6"},
+		{"2x^2", "18", "18"},
+		{"2x²", "18", "18"},
+		{"2(x+1)", "8", "8"},
+		{"(a+b)(a-b)", "15", "15"},
+		{"2 pi r", "3.141592653589793", "3.141592653589793"},
+		{"2√a", "4", "4"},
+		{"(x)(x+1)^2", "48", "48"},
+		{"1/2x", "1.5", "0.16666666666666666"},
+		{"a/2(b+1)", "4", "1"},
+		{"2x!", "12", "12"},
+		{"-2x^2", "-18", "-18"},
+	}
+
+	for _, precedence := range []ImplicitPrecedence{ImplicitLoose, ImplicitTight} {
+		for _, test := range cases {
+			program, err := Compile(test.src, WithImplicit(precedence))
+			if err != nil {
+				t.Errorf("%s (%s): %v", test.src, precedence, err)
+				continue
+			}
+
+			value, err := program.Run(context.Background(), Env{Bindings: bindings})
+			if err != nil {
+				t.Errorf("%s (%s): %v", test.src, precedence, err)
+				continue
+			}
+
+			expected := test.loose
+			if precedence == ImplicitTight {
+				expected = test.tight
+			}
+			if value.String() != expected {
+				t.Errorf("%s (%s) = %s, want %s", test.src, precedence, value, expected)
+			}
+		}
+	}
+
+	check_eval_cases(t, []eval_case{
+		{Src: "sin(0)", Expected: "0"},
+		{Src: "x(2)", Err: "function 'x' does not exist"},
+		{Src: "2 3", Err: "invalid"},
+	})
+}
+
+func TestPercentages(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "10%", Expected: "0.1"},
+		{Src: "200 + 10%", Expected: "220"},
+		{Src: "200 - 10%", Expected: "180"},
+		{Src: "1 + 2 + 10%", Expected: "3.3"},
+		{Src: "200 * 10%", Expected: "20"},
+		{Src: "50% of 80", Expected: "40"},
+		{Src: "20% off 80", Expected: "64"},
+		{Src: "10% of 10% of 100", Expected: "1"},
+		{Src: "50% of 3 m", Expected: "1.5 m"},
+		{Src: "[100, 200] + 10%", Expected: "[110, 220]"},
+		// only a percentage right at the sum is a share of its left
+		{Src: "200 + (10%)", Expected: "200.1"},
+		// a % with an operand after it is still the modulo
+		{Src: "10 % 3", Expected: "1"},
+		{Src: "10 % 3 + 1", Expected: "2"},
+		{Src: "200 + 15%", Opts: []Option{WithNumericMode(RationalMode)}, Expected: "230"},
+		{Src: "19.99 - 15%", Opts: []Option{WithNumericMode(DecimalMode)}, Expected: "16.99"},
+		{Src: "200 + 10%", Opts: []Option{WithNumericMode(IntegerMode)}, Expected: "220"},
+	})
+
+	ops := map[string]Op{
+		"200 + 10%": OpAddPercent,
+		"200 - 10%": OpSubPercent,
+		"50% of 80": OpPercentOf,
+		"10 % 3":    OpMod,
+	}
+	for src, op := range ops {
+		parser := NewParser([]byte(src), "calc")
+		expr, err := parser.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		compiler := NewCompiler(expr)
+		if _, err := compiler.Compile(); err != nil {
+			t.Fatal(err)
+		}
+
+		found := false
+		for _, instruction := range compiler.Instructions {
+			found = found || instruction.Op == op
+		}
+		if !found {
+			t.Errorf("%s does not compile to %s", src, op)
+		}
+	}
+}
@@ -1,12 +1,18 @@
-package main
+package calc
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"math/big"
+	"strconv"
 	"strings"
 )
 
 type Object interface {
 	GetValue() interface{}
+	String() string
 }
 
 type Float64Object struct {
@@ -17,18 +23,224 @@ func (obj Float64Object) GetValue() interface{} {
 	return obj.Value
 }
 
+func (obj Float64Object) String() string {
+	return strconv.FormatFloat(obj.Value, 'g', -1, 64)
+}
+
+// object_tag identifies the type of a serialized constant in the archive.
+type object_tag byte
+
+const (
+	tag_float64 object_tag = iota
+	tag_big_int
+	tag_big_rat
+	tag_big_float
+	tag_decimal
+	tag_int
+	tag_complex
+	tag_list
+	tag_quantity
+)
+
+func serialize_object(obj Object) (object_tag, []byte, error) {
+	switch value := obj.(type) {
+	case Float64Object:
+		return tag_float64, float64_to_bytes(value.Value), nil
+	case BigIntObject:
+		data, err := value.Value.GobEncode()
+		return tag_big_int, data, err
+	case BigRatObject:
+		data, err := value.Value.GobEncode()
+		return tag_big_rat, data, err
+	case BigFloatObject:
+		data, err := value.Value.GobEncode()
+		return tag_big_float, data, err
+	case DecimalObject:
+		data, err := value.Unscaled.GobEncode()
+		return tag_decimal, append(uint32_to_bytes(uint32(value.Scale)), data...), err
+	case IntObject:
+		data := binary.LittleEndian.AppendUint64(nil, uint64(value.Value))
+		if value.Unsigned {
+			return tag_int, append(data, 1), nil
+		}
+		return tag_int, append(data, 0), nil
+	case ComplexObject:
+		data := float64_to_bytes(real(value.Value))
+		return tag_complex, append(data, float64_to_bytes(imag(value.Value))...), nil
+	case ListObject:
+		data, err := serialize_objects(value.Values)
+		return tag_list, data, err
+	case QuantityObject:
+		data, err := serialize_objects([]Object{value.Value})
+		return tag_quantity, append(serialize_unit(value.Unit), data...), err
+	default:
+		return 0, nil, fmt.Errorf("cannot serialize constant %s", obj)
+	}
+}
+
+func deserialize_object(tag object_tag, data []byte) (Object, error) {
+	switch tag {
+	case tag_float64:
+		if len(data) != 8 {
+			return nil, errors.New("broken archive")
+		}
+		return Float64Object{bytes_to_float64(data)}, nil
+	case tag_big_int:
+		value := new(big.Int)
+		err := value.GobDecode(data)
+		return BigIntObject{value}, err
+	case tag_big_rat:
+		value := new(big.Rat)
+		err := value.GobDecode(data)
+		return BigRatObject{value}, err
+	case tag_big_float:
+		value := new(big.Float)
+		err := value.GobDecode(data)
+		return BigFloatObject{value}, err
+	case tag_decimal:
+		if len(data) < 4 {
+			return nil, errors.New("broken archive")
+		}
+		value := new(big.Int)
+		err := value.GobDecode(data[4:])
+		return DecimalObject{Unscaled: value, Scale: int32(bytes_to_uint32(data[:4]))}, err
+	case tag_int:
+		if len(data) != 9 {
+			return nil, errors.New("broken archive")
+		}
+		return IntObject{Value: int64(binary.LittleEndian.Uint64(data)), Unsigned: data[8] == 1}, nil
+	case tag_complex:
+		if len(data) != 16 {
+			return nil, errors.New("broken archive")
+		}
+		return ComplexObject{complex(bytes_to_float64(data[:8]), bytes_to_float64(data[8:]))}, nil
+	case tag_list:
+		values, err := deserialize_objects(data)
+		return ListObject{values}, err
+	case tag_quantity:
+		unit, rest, err := deserialize_unit(data)
+		if err != nil {
+			return nil, err
+		}
+		values, err := deserialize_objects(rest)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 1 {
+			return nil, errors.New("broken archive")
+		}
+		return QuantityObject{values[0], unit}, nil
+	default:
+		return nil, fmt.Errorf("unknown constant type %d", tag)
+	}
+}
+
+// serialize_unit writes the number of terms of a unit followed by the power
+// and the length prefixed symbol of every term.
+func serialize_unit(unit Unit) []byte {
+	result := uint32_to_bytes(uint32(len(unit.Terms)))
+
+	for _, term := range unit.Terms {
+		result = append(result, uint32_to_bytes(uint32(int32(term.Power)))...)
+		result = append(result, uint32_to_bytes(uint32(len(term.Symbol)))...)
+		result = append(result, term.Symbol...)
+	}
+
+	return result
+}
+
+// deserialize_unit reads a unit off the front of data and returns the rest,
+// every symbol has to be in the unit registry.
+func deserialize_unit(data []byte) (Unit, []byte, error) {
+	if len(data) < 4 {
+		return Unit{}, nil, errors.New("broken archive")
+	}
+	count := int(bytes_to_uint32(data[:4]))
+	data = data[4:]
+
+	unit := Unit{}
+	for range count {
+		if len(data) < 8 {
+			return Unit{}, nil, errors.New("broken archive")
+		}
+		power := int(int32(bytes_to_uint32(data[:4])))
+		length := int(bytes_to_uint32(data[4:8]))
+		data = data[8:]
+
+		if length > len(data) {
+			return Unit{}, nil, errors.New("broken archive")
+		}
+		symbol := string(data[:length])
+		data = data[length:]
+
+		if _, ok := lookup_unit(symbol); !ok {
+			return Unit{}, nil, fmt.Errorf("unknown unit '%s'", symbol)
+		}
+		unit.Terms = append(unit.Terms, unit_term{symbol, power})
+	}
+
+	return unit, data, nil
+}
+
+// serialize_objects writes every object as a type tag, the length of its
+// payload and the payload itself.
+func serialize_objects(objects []Object) ([]byte, error) {
+	result := []byte{}
+
+	for _, obj := range objects {
+		tag, data, err := serialize_object(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, byte(tag))
+		result = append(result, uint32_to_bytes(uint32(len(data)))...)
+		result = append(result, data...)
+	}
+
+	return result, nil
+}
+
+func deserialize_objects(data []byte) ([]Object, error) {
+	result := []Object{}
+
+	for offset := 0; offset < len(data); {
+		if offset+5 > len(data) {
+			return nil, errors.New("broken archive")
+		}
+		tag := object_tag(data[offset])
+		length := int(bytes_to_uint32(data[offset+1 : offset+5]))
+		offset += 5
+
+		if length > len(data)-offset {
+			return nil, errors.New("broken archive")
+		}
+		value, err := deserialize_object(tag, data[offset:offset+length])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+		offset += length
+	}
+
+	return result, nil
+}
+
 type ConstantPool struct {
-	Values  [512]Object
-	pointer int
+	Values []Object
 }
 
+// Has compares constants by their serialized form, which tells apart 0 and
+// -0 and works for the big number types that are held by pointer.
 func (p ConstantPool) Has(value Object) int {
-	for i, v := range p.Values {
-		if v == nil {
-			return -1
-		}
+	tag, data, err := serialize_object(value)
+	if err != nil {
+		return -1
+	}
 
-		if v.GetValue() == value.GetValue() {
+	for i, v := range p.Values {
+		v_tag, v_data, err := serialize_object(v)
+		if err == nil && v_tag == tag && bytes.Equal(v_data, data) {
 			return i
 		}
 	}
@@ -43,10 +255,9 @@ func (p *ConstantPool) Add(value Object) int {
 		return has
 	}
 
-	p.Values[p.pointer] = value
-	p.pointer++
+	p.Values = append(p.Values, value)
 
-	return p.pointer - 1
+	return len(p.Values) - 1
 }
 
 func (p *ConstantPool) Get(index int) Object {
@@ -57,29 +268,20 @@ func (p ConstantPool) String() string {
 	result := []string{}
 
 	for i, constant := range p.Values {
-		if constant == nil {
-			break
-		}
-
 		result = append(result, fmt.Sprintf("%d: %+v", i, constant))
 	}
 
 	return fmt.Sprintf("[%s]", strings.Join(result, " "))
 }
 
-func (p ConstantPool) Serialize() []byte {
-	result := []byte{}
-
-	for _, obj := range p.Values[:p.pointer] {
-		result = append(result, float64_to_bytes(obj.GetValue().(float64))...)
-	}
-
-	return result
+// Serialize writes every constant as a type tag, the length of its payload
+// and the payload itself.
+func (p ConstantPool) Serialize() ([]byte, error) {
+	return serialize_objects(p.Values)
 }
 
 func NewConstantPool() ConstantPool {
 	return ConstantPool{
-		Values:  [512]Object{},
-		pointer: 0,
+		Values: []Object{},
 	}
 }
@@ -0,0 +1,668 @@
+package calc
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// dimension counts the powers of the SI base quantities length, mass, time,
+// electric current, temperature, amount of substance and luminous intensity.
+type dimension [7]int
+
+func (d dimension) add(other dimension, sign int) dimension {
+	for i := range d {
+		d[i] += sign * other[i]
+	}
+
+	return d
+}
+
+func (d dimension) scale(n int) dimension {
+	for i := range d {
+		d[i] *= n
+	}
+
+	return d
+}
+
+func (d dimension) zero() bool {
+	return d == dimension{}
+}
+
+// base_unit_symbols are the SI base units in the order of dimension.
+var base_unit_symbols = [len(dimension{})]string{"m", "kg", "s", "A", "K", "mol", "cd"}
+
+// unit expresses a dimension in SI base units.
+func (d dimension) unit() Unit {
+	result := Unit{}
+
+	for i, power := range d {
+		if power != 0 {
+			result.Terms = append(result.Terms, unit_term{base_unit_symbols[i], power})
+		}
+	}
+
+	return result
+}
+
+// unit_def is an entry of the unit registry, factor converts the unit to the
+// SI base units of its dimension. Prefixable units also exist with any of the
+// SI prefixes, such as km or mA.
+type unit_def struct {
+	dim        dimension
+	factor     *big.Rat
+	prefixable bool
+}
+
+func unit_rat(value string) *big.Rat {
+	result, ok := new(big.Rat).SetString(value)
+	if !ok {
+		panic(fmt.Sprintf("invalid unit factor %s", value))
+	}
+
+	return result
+}
+
+var (
+	dim_length      = dimension{1, 0, 0, 0, 0, 0, 0}
+	dim_mass        = dimension{0, 1, 0, 0, 0, 0, 0}
+	dim_time        = dimension{0, 0, 1, 0, 0, 0, 0}
+	dim_current     = dimension{0, 0, 0, 1, 0, 0, 0}
+	dim_temperature = dimension{0, 0, 0, 0, 1, 0, 0}
+	dim_amount      = dimension{0, 0, 0, 0, 0, 1, 0}
+	dim_luminosity  = dimension{0, 0, 0, 0, 0, 0, 1}
+	dim_volume      = dimension{3, 0, 0, 0, 0, 0, 0}
+	dim_speed       = dimension{1, 0, -1, 0, 0, 0, 0}
+	dim_frequency   = dimension{0, 0, -1, 0, 0, 0, 0}
+	dim_force       = dimension{1, 1, -2, 0, 0, 0, 0}
+	dim_pressure    = dimension{-1, 1, -2, 0, 0, 0, 0}
+	dim_energy      = dimension{2, 1, -2, 0, 0, 0, 0}
+	dim_power       = dimension{2, 1, -3, 0, 0, 0, 0}
+	dim_charge      = dimension{0, 0, 1, 1, 0, 0, 0}
+	dim_voltage     = dimension{2, 1, -3, -1, 0, 0, 0}
+	dim_resistance  = dimension{2, 1, -3, -2, 0, 0, 0}
+)
+
+var units = map[string]unit_def{
+	// SI base units, the kilogram is the prefixed gram
+	"m":   {dim_length, unit_rat("1"), true},
+	"g":   {dim_mass, unit_rat("1/1000"), true},
+	"s":   {dim_time, unit_rat("1"), true},
+	"A":   {dim_current, unit_rat("1"), true},
+	"K":   {dim_temperature, unit_rat("1"), true},
+	"mol": {dim_amount, unit_rat("1"), true},
+	"cd":  {dim_luminosity, unit_rat("1"), true},
+
+	// derived SI units
+	"Hz":  {dim_frequency, unit_rat("1"), true},
+	"N":   {dim_force, unit_rat("1"), true},
+	"Pa":  {dim_pressure, unit_rat("1"), true},
+	"J":   {dim_energy, unit_rat("1"), true},
+	"W":   {dim_power, unit_rat("1"), true},
+	"C":   {dim_charge, unit_rat("1"), true},
+	"V":   {dim_voltage, unit_rat("1"), true},
+	"ohm": {dim_resistance, unit_rat("1"), true},
+
+	// units accepted alongside the SI
+	"L":    {dim_volume, unit_rat("1/1000"), true},
+	"t":    {dim_mass, unit_rat("1000"), true},
+	"min":  {dim_time, unit_rat("60"), false},
+	"h":    {dim_time, unit_rat("3600"), false},
+	"day":  {dim_time, unit_rat("86400"), false},
+	"week": {dim_time, unit_rat("604800"), false},
+	"bar":  {dim_pressure, unit_rat("100000"), true},
+	"atm":  {dim_pressure, unit_rat("101325"), false},
+	"eV":   {dim_energy, unit_rat("1.602176634e-19"), true},
+	"cal":  {dim_energy, unit_rat("4.184"), true},
+
+	// imperial and US customary units, in is also the conversion operator so
+	// the inch may be spelled out
+	"in":   {dim_length, unit_rat("0.0254"), false},
+	"inch": {dim_length, unit_rat("0.0254"), false},
+	"ft":   {dim_length, unit_rat("0.3048"), false},
+	"yd":   {dim_length, unit_rat("0.9144"), false},
+	"mi":   {dim_length, unit_rat("1609.344"), false},
+	"nmi":  {dim_length, unit_rat("1852"), false},
+	"oz":   {dim_mass, unit_rat("0.028349523125"), false},
+	"lb":   {dim_mass, unit_rat("0.45359237"), false},
+	"gal":  {dim_volume, unit_rat("0.003785411784"), false},
+	"mph":  {dim_speed, unit_rat("0.44704"), false},
+	"psi":  {dim_pressure, unit_rat("44482216152605/6451600000"), false},
+}
+
+// offset_units are the temperature scales whose zero is not absolute zero,
+// which are left out on purpose. Units only scale values, while 20 degC is
+// 293.15 K and twice 20 degC is not 40 degC, so temperatures are written in K
+// and naming one of these fails instead of multiplying by an unknown name.
+var offset_units = map[string]bool{
+	"degC":       true,
+	"degF":       true,
+	"celsius":    true,
+	"fahrenheit": true,
+}
+
+func offset_unit_err(token Token) error {
+	return fmt.Errorf("unsupported offset temperature scale '%s' at %d:%d, temperatures are written in K", token.Literal, token.Location.Line, token.Location.Col)
+}
+
+// unit_prefixes are the SI prefixes, da comes before d so decametres are not
+// read as decimetres of a unit called am.
+var unit_prefixes = []struct {
+	symbol string
+	factor *big.Rat
+}{
+	{"Q", unit_rat("1e30")},
+	{"R", unit_rat("1e27")},
+	{"Y", unit_rat("1e24")},
+	{"Z", unit_rat("1e21")},
+	{"E", unit_rat("1e18")},
+	{"P", unit_rat("1e15")},
+	{"T", unit_rat("1e12")},
+	{"G", unit_rat("1e9")},
+	{"M", unit_rat("1e6")},
+	{"k", unit_rat("1e3")},
+	{"h", unit_rat("1e2")},
+	{"da", unit_rat("1e1")},
+	{"d", unit_rat("1e-1")},
+	{"c", unit_rat("1e-2")},
+	{"m", unit_rat("1e-3")},
+	{"u", unit_rat("1e-6")},
+	{"µ", unit_rat("1e-6")},
+	{"n", unit_rat("1e-9")},
+	{"p", unit_rat("1e-12")},
+	{"f", unit_rat("1e-15")},
+	{"a", unit_rat("1e-18")},
+	{"z", unit_rat("1e-21")},
+	{"y", unit_rat("1e-24")},
+	{"r", unit_rat("1e-27")},
+	{"q", unit_rat("1e-30")},
+}
+
+// lookup_unit finds a unit symbol in the registry, trying the symbol as it
+// is before reading a prefix off it.
+func lookup_unit(symbol string) (unit_def, bool) {
+	if def, ok := units[symbol]; ok {
+		return def, true
+	}
+
+	for _, prefix := range unit_prefixes {
+		base, ok := strings.CutPrefix(symbol, prefix.symbol)
+		if !ok {
+			continue
+		}
+
+		if def, ok := units[base]; ok && def.prefixable {
+			return unit_def{def.dim, new(big.Rat).Mul(prefix.factor, def.factor), false}, true
+		}
+	}
+
+	return unit_def{}, false
+}
+
+type unit_term struct {
+	Symbol string
+	Power  int
+}
+
+// Unit is a product of registry units raised to integer powers, such as
+// km/h or kg*m/s^2. The zero Unit belongs to plain numbers.
+type Unit struct {
+	Terms []unit_term
+}
+
+func unit_of(symbol string, power int) Unit {
+	return Unit{[]unit_term{{symbol, power}}}
+}
+
+func (u Unit) String() string {
+	numerator, denominator := []string{}, []string{}
+
+	for _, term := range u.Terms {
+		if term.Power > 0 {
+			numerator = append(numerator, unit_power_string(term.Symbol, term.Power))
+		} else {
+			denominator = append(denominator, unit_power_string(term.Symbol, -term.Power))
+		}
+	}
+
+	result := strings.Join(numerator, "*")
+	if result == "" {
+		result = "1"
+	}
+
+	for _, term := range denominator {
+		result += "/" + term
+	}
+
+	return result
+}
+
+func unit_power_string(symbol string, power int) string {
+	if power == 1 {
+		return symbol
+	}
+
+	return symbol + "^" + strconv.Itoa(power)
+}
+
+// describe names a unit in error messages.
+func (u Unit) describe() string {
+	if len(u.Terms) == 0 {
+		return "a plain number"
+	}
+
+	return fmt.Sprintf("'%s'", u)
+}
+
+func (u Unit) dimension() dimension {
+	result := dimension{}
+
+	for _, term := range u.Terms {
+		def, _ := lookup_unit(term.Symbol)
+		result = result.add(def.dim.scale(term.Power), 1)
+	}
+
+	return result
+}
+
+// factor converts the unit to the SI base units of its dimension.
+func (u Unit) factor() *big.Rat {
+	result := big.NewRat(1, 1)
+
+	for _, term := range u.Terms {
+		def, _ := lookup_unit(term.Symbol)
+		for range abs_int(term.Power) {
+			if term.Power > 0 {
+				result.Mul(result, def.factor)
+			} else {
+				result.Quo(result, def.factor)
+			}
+		}
+	}
+
+	return result
+}
+
+// mul multiplies two units, or divides them for a sign of -1. Powers of the
+// same symbol are combined and dropped once they cancel.
+func (u Unit) mul(other Unit, sign int) Unit {
+	terms := make([]unit_term, len(u.Terms))
+	copy(terms, u.Terms)
+
+outer:
+	for _, term := range other.Terms {
+		for i := range terms {
+			if terms[i].Symbol == term.Symbol {
+				terms[i].Power += sign * term.Power
+				continue outer
+			}
+		}
+		terms = append(terms, unit_term{term.Symbol, sign * term.Power})
+	}
+
+	result := Unit{}
+	for _, term := range terms {
+		if term.Power != 0 {
+			result.Terms = append(result.Terms, term)
+		}
+	}
+
+	return result
+}
+
+func (u Unit) pow(n int) Unit {
+	result := Unit{}
+
+	if n != 0 {
+		for _, term := range u.Terms {
+			result.Terms = append(result.Terms, unit_term{term.Symbol, term.Power * n})
+		}
+	}
+
+	return result
+}
+
+// root takes the n'th root of a unit, which only exists when every power is
+// a multiple of n.
+func (u Unit) root(n int) (Unit, bool) {
+	result := Unit{}
+
+	for _, term := range u.Terms {
+		if term.Power%n != 0 {
+			return Unit{}, false
+		}
+		result.Terms = append(result.Terms, unit_term{term.Symbol, term.Power / n})
+	}
+
+	return result, true
+}
+
+func abs_int(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+// QuantityObject is a number with a unit of measure.
+type QuantityObject struct {
+	Value Object
+	Unit  Unit
+}
+
+func (obj QuantityObject) GetValue() interface{} {
+	return obj.Value.GetValue()
+}
+
+func (obj QuantityObject) String() string {
+	return fmt.Sprintf("%s %s", obj.Value, obj.Unit)
+}
+
+func any_quantity(args []Object) bool {
+	for _, arg := range args {
+		if _, ok := arg.(QuantityObject); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// as_quantity reads plain numbers as quantities without a unit.
+func as_quantity(obj Object) QuantityObject {
+	if quantity, ok := obj.(QuantityObject); ok {
+		return quantity
+	}
+
+	return QuantityObject{Value: obj}
+}
+
+// make_quantity attaches a unit to a value, quantities whose dimensions
+// cancel out become plain numbers.
+func make_quantity(ctx *CallContext, value Object, unit Unit) (Object, error) {
+	if len(unit.Terms) > 0 && unit.dimension().zero() {
+		return convert_value(ctx, value, unit, Unit{})
+	}
+
+	if len(unit.Terms) == 0 {
+		return value, nil
+	}
+
+	return QuantityObject{value, unit}, nil
+}
+
+// convert_value converts a value between two units of the same dimension. It
+// multiplies before it divides so the integer mode only truncates once.
+func convert_value(ctx *CallContext, value Object, from, to Unit) (Object, error) {
+	ratio := new(big.Rat).Quo(from.factor(), to.factor())
+	if ratio.Cmp(big.NewRat(1, 1)) == 0 {
+		return value, nil
+	}
+
+	value, err := binary_op(ctx, OpMul, value, BigIntObject{ratio.Num()})
+	if err != nil {
+		return nil, err
+	}
+
+	return binary_op(ctx, OpDiv, value, BigIntObject{ratio.Denom()})
+}
+
+// quantity_op applies a binary operation to operands of which at least one
+// has a unit. Sums and remainders need operands of the same dimension and
+// are expressed in the unit of the left operand, products combine units.
+func quantity_op(ctx *CallContext, op Op, left, right Object) (Object, error) {
+	l, r := as_quantity(left), as_quantity(right)
+
+	switch op {
+	case OpAdd, OpSub, OpMod, OpIntDiv:
+		if l.Unit.dimension() != r.Unit.dimension() {
+			return nil, DimensionError{Reason: fmt.Sprintf("incompatible units %s and %s", l.Unit.describe(), r.Unit.describe())}
+		}
+
+		converted, err := convert_value(ctx, r.Value, r.Unit, l.Unit)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := binary_op(ctx, op, l.Value, converted)
+		if err != nil {
+			return nil, err
+		}
+
+		return make_quantity(ctx, value, l.Unit)
+	case OpMul, OpDiv:
+		value, err := binary_op(ctx, op, l.Value, r.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		sign := 1
+		if op == OpDiv {
+			sign = -1
+		}
+
+		return make_quantity(ctx, value, l.Unit.mul(r.Unit, sign))
+	case OpPow:
+		if len(r.Unit.Terms) > 0 {
+			return nil, DimensionError{Reason: fmt.Sprintf("exponents cannot have units but got %s", right)}
+		}
+
+		n, ok := exact_int(r.Value)
+		if !ok || !n.IsInt64() || abs_int(int(n.Int64())) > max_unit_power {
+			return nil, DimensionError{Reason: fmt.Sprintf("quantities can only be raised to small integer powers but got %s", right)}
+		}
+
+		value, err := binary_op(ctx, op, l.Value, r.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return make_quantity(ctx, value, l.Unit.pow(int(n.Int64())))
+	}
+
+	return nil, fmt.Errorf("unsupported operation %s for quantities with units", op)
+}
+
+// max_unit_power bounds the powers units are raised to.
+const max_unit_power = 64
+
+// convert_quantity expresses a quantity in another unit of the same
+// dimension, lists are converted element by element.
+func convert_quantity(ctx *CallContext, obj Object, unit Unit) (Object, error) {
+	if list, ok := obj.(ListObject); ok {
+		values := make([]Object, len(list.Values))
+		for i, value := range list.Values {
+			converted, err := convert_quantity(ctx, value, unit)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = converted
+		}
+
+		return ListObject{values}, nil
+	}
+
+	quantity := as_quantity(obj)
+	if quantity.Unit.dimension() != unit.dimension() {
+		return nil, DimensionError{Reason: fmt.Sprintf("cannot convert %s to %s", quantity.Unit.describe(), unit.describe())}
+	}
+
+	value, err := convert_value(ctx, quantity.Value, quantity.Unit, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	return QuantityObject{value, unit}, nil
+}
+
+// call_quantity calls a builtin with quantity arguments. Arguments whose
+// dimensions cancel out are passed as plain numbers, builtins with a UnitRoot
+// take a quantity as their first argument and every other one is rejected.
+func (d BuiltinFnDescriptor) call_quantity(ctx *CallContext, args []Object) (Object, error) {
+	plain := make([]Object, len(args))
+	unit := Unit{}
+
+	for i, arg := range args {
+		quantity, ok := arg.(QuantityObject)
+		if !ok {
+			plain[i] = arg
+			continue
+		}
+
+		if quantity.Unit.dimension().zero() {
+			value, err := convert_value(ctx, quantity.Value, quantity.Unit, Unit{})
+			if err != nil {
+				return nil, err
+			}
+			plain[i] = value
+			continue
+		}
+
+		if i > 0 || d.UnitRoot == 0 {
+			return nil, DomainError{Name: builtin_fns.name_of(d.Pointer), Reason: fmt.Sprintf("cannot take quantities with units but got %s", arg)}
+		}
+		plain[i], unit = quantity.Value, quantity.Unit
+	}
+
+	result, err := d.Call(ctx, plain)
+	if err != nil || len(unit.Terms) == 0 {
+		return result, err
+	}
+
+	root, ok := unit.root(d.UnitRoot)
+	if !ok {
+		return nil, DomainError{Name: builtin_fns.name_of(d.Pointer), Reason: fmt.Sprintf("cannot take the root of the unit %s", unit.describe())}
+	}
+
+	return make_quantity(ctx, result, root)
+}
+
+// check_units works out the dimension of expressions whose units are known
+// at compile time and reports sums and conversions that mix dimensions. It
+// returns false for expressions whose dimension is only known at runtime.
+func (c *Compiler) check_units(e Expr) (dimension, bool, error) {
+	switch expr := e.(type) {
+	case FloatLiteralExpr, ImaginaryLiteralExpr, ConstLiteralExpr:
+		return dimension{}, true, nil
+	case QuantityExpr:
+		return expr.Unit.dimension(), true, nil
+	case GroupExpr:
+		return c.check_units(expr.Expr)
+	case UnaryExpr:
+		return c.check_units(expr.Expr)
+	case PercentExpr:
+		return c.check_units(expr.Expr)
+	case AngleModeExpr:
+		return c.check_units(expr.Expr)
+	case ConvertExpr:
+		dim, ok, err := c.check_units(expr.Expr)
+		if err != nil {
+			return dim, ok, err
+		}
+
+		if ok && dim != expr.Unit.dimension() {
+			return dim, ok, DimensionError{Reason: fmt.Sprintf("cannot convert %s to %s", static_unit(expr.Expr, dim), expr.Unit.describe()), Location: expr.Location}
+		}
+
+		return expr.Unit.dimension(), true, nil
+	case BinaryExpr:
+		return c.check_binary_units(expr)
+	case FnCallExpr:
+		for _, arg := range expr.Args {
+			if _, _, err := c.check_units(arg); err != nil {
+				return dimension{}, false, err
+			}
+		}
+	case ListExpr:
+		for _, item := range expr.Items {
+			if _, _, err := c.check_units(item); err != nil {
+				return dimension{}, false, err
+			}
+		}
+	case IndexExpr:
+		if _, _, err := c.check_units(expr.Expr); err != nil {
+			return dimension{}, false, err
+		}
+		if _, _, err := c.check_units(expr.Index); err != nil {
+			return dimension{}, false, err
+		}
+	}
+
+	return dimension{}, false, nil
+}
+
+func (c *Compiler) check_binary_units(expr BinaryExpr) (dimension, bool, error) {
+	left, left_ok, err := c.check_units(expr.Left)
+	if err != nil {
+		return left, false, err
+	}
+
+	right, right_ok, err := c.check_units(expr.Right)
+	if err != nil {
+		return right, false, err
+	}
+
+	if !left_ok || !right_ok {
+		return dimension{}, false, nil
+	}
+
+	switch expr.Op {
+	case OpTypeAdd, OpTypeSub, OpTypeMod, OpTypeIntDiv:
+		if left != right {
+			return left, false, DimensionError{Reason: fmt.Sprintf("incompatible units %s and %s", static_unit(expr.Left, left), static_unit(expr.Right, right)), Location: expr.Location}
+		}
+		return left, true, nil
+	case OpTypeAddPercent, OpTypeSubPercent, OpTypeOff:
+		base, percent := left, right
+		percent_expr := expr.Right
+		if expr.Op == OpTypeOff {
+			base, percent = right, left
+			percent_expr = expr.Left
+		}
+
+		if !percent.zero() {
+			return base, false, DimensionError{Reason: fmt.Sprintf("percentages cannot have units but got %s", static_unit(percent_expr, percent)), Location: expr.Location}
+		}
+		return base, true, nil
+	case OpTypeMul, OpTypeOf:
+		return left.add(right, 1), true, nil
+	case OpTypeDiv:
+		return left.add(right, -1), true, nil
+	case OpTypePow:
+		if !right.zero() {
+			return left, false, DimensionError{Reason: fmt.Sprintf("exponents cannot have units but got %s", static_unit(expr.Right, right)), Location: expr.Location}
+		}
+
+		if left.zero() {
+			return left, true, nil
+		}
+
+		if exponent, ok := expr.Right.(FloatLiteralExpr); ok && exponent.Value == float64(int(exponent.Value)) {
+			return left.scale(int(exponent.Value)), true, nil
+		}
+	}
+
+	if left.zero() && right.zero() {
+		return left, true, nil
+	}
+
+	return dimension{}, false, nil
+}
+
+// static_unit names the unit of an expression in compile time errors, the
+// unit written in the source when there is one and SI base units otherwise.
+func static_unit(e Expr, dim dimension) string {
+	switch expr := e.(type) {
+	case QuantityExpr:
+		return expr.Unit.describe()
+	case ConvertExpr:
+		return expr.Unit.describe()
+	case GroupExpr:
+		return static_unit(expr.Expr, dim)
+	}
+
+	return dim.unit().describe()
+}
@@ -0,0 +1,109 @@
+package calc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestInchOrConversion(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "12 in", Expected: "12 in"},
+		{Src: "12 in to cm", Expected: "30.48 cm"},
+		{Src: "12 inch to cm", Expected: "30.48 cm"},
+		{Src: "2 in + 1 ft", Expected: "14 in"},
+		{Src: "12 in^2 to cm^2", Expected: "77.4192 cm^2"},
+		{Src: "1 ft in in", Expected: "12 in"},
+		{Src: "5 ft in cm", Expected: "152.4 cm"},
+		{Src: "254 cm to in", Expected: "100 in"},
+		{Src: "12 in cm", Err: "cannot convert a plain number to 'cm'"},
+	})
+}
+
+func TestOffsetTemperatures(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "20 degC", Err: "unsupported offset temperature scale 'degC' at 1:4"},
+		{Src: "300 K to degF", Err: "unsupported offset temperature scale 'degF' at 1:10"},
+		{Src: "300 K to K", Expected: "300 K"},
+		{Src: "1 mK to K", Expected: "0.001 K"},
+	})
+}
+
+func TestVariablesBeforeUnits(t *testing.T) {
+	bindings := map[string]float64{"t": 3, "h": 2, "m": 5}
+
+	cases := []struct {
+		src      string
+		opts     []Option
+		expected string
+	}{
+		{"2t", nil, "2 t"},
+		{"2 h", nil, "2 h"},
+		{"2t", []Option{WithParams("t")}, "6"},
+		{"2 h", []Option{WithParams("h")}, "4"},
+		{"2t + t", nil, "9"},
+		{"t + 2t", nil, "9"},
+		{"2 h * h + h", nil, "10"},
+		{"10 km/h * h", nil, "10 km"},
+		{"10 km/h * h", []Option{WithParams("h")}, "10 km"},
+		{"3 m + m", nil, "20"},
+		{"m * 1 km to m", nil, "5000 m"},
+		{"2 s + 1 min", []Option{WithParams("t")}, "62 s"},
+	}
+
+	for _, test := range cases {
+		program, err := Compile(test.src, test.opts...)
+		if err != nil {
+			t.Errorf("%s: %v", test.src, err)
+			continue
+		}
+
+		value, err := program.Run(context.Background(), Env{Bindings: bindings})
+		if err != nil {
+			t.Errorf("%s: %v", test.src, err)
+			continue
+		}
+
+		if value.String() != test.expected {
+			t.Errorf("%s = %s, want %s", test.src, value, test.expected)
+		}
+	}
+}
+
+func TestQuantities(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "5 km + 300 m", Expected: "5.3 km"},
+		{Src: "2 m * 3 m", Expected: "6 m^2"},
+		{Src: "6 m^2 / 2 m", Expected: "3 m"},
+		{Src: "(2 m)^2", Expected: "4 m^2"},
+		{Src: "sqrt(9 m^2)", Expected: "3 m"},
+		{Src: "1 km / 1 m", Expected: "1000"},
+		{Src: "9.8 m/s^2 * 2 s", Expected: "19.6 m/s"},
+		{Src: "1 m + 1 mm", Opts: []Option{WithNumericMode(RationalMode)}, Expected: "1.001 m"},
+	})
+}
+
+func TestUnitConversions(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "60 mi/h to m/s", Expected: "26.8224 m/s"},
+		{Src: "1 h to min", Expected: "60 min"},
+		{Src: "1 atm to Pa", Expected: "101325 Pa"},
+		{Src: "1 mi to km", Expected: "1.609344 km"},
+		{Src: "10 N to kg*m/s^2", Expected: "10 kg*m/s^2"},
+		{Src: "5 km + 300 m to m", Expected: "5300 m"},
+		{Src: "5 km + 300 m in m", Expected: "5300 m"},
+		{Src: "[1 m, 2 m] to cm", Expected: "[100 cm, 200 cm]"},
+		{Src: "1 m to zz", Err: "unknown unit 'zz' at 1:8"},
+	})
+}
+
+func TestDimensionErrors(t *testing.T) {
+	check_eval_cases(t, []eval_case{
+		{Src: "3 kg + 2 s", Err: "incompatible units 'kg' and 's' at 1:6"},
+		{Src: "1 m + 1", Err: "incompatible units 'm' and a plain number at 1:5"},
+		{Src: "5 km to s", Err: "cannot convert 'km' to 's' at 1:6"},
+		{Src: "2 m ^ 0.5", Err: "quantities can only be raised to small integer powers but got 0.5 at 1:5"},
+		{Src: "2^(1 m)", Err: "exponents cannot have units but got 'm' at 1:2"},
+		{Src: "sqrt(2 m)", Err: "function 'sqrt' cannot take the root of the unit 'm'"},
+		{Src: "sin(1 m)", Err: "function 'sin' cannot take quantities with units but got 1 m"},
+	})
+}
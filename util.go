@@ -1,4 +1,4 @@
-package main
+package calc
 
 import (
 	"bytes"
@@ -16,6 +16,13 @@ func bytes_to_uint32(b []byte) uint32 {
 	return binary.LittleEndian.Uint32(b)
 }
 
+func bool_to_byte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 func float64_to_bytes(n float64) []byte {
 	buffer := bytes.Buffer{}
 	binary.Write(&buffer, binary.LittleEndian, n)
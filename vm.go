@@ -1,95 +1,296 @@
-package main
+package calc
 
 import (
-	"math"
+	"context"
+	"fmt"
+	"math/rand/v2"
 )
 
+// Stack grows with the values pushed on it, however many arguments a call or
+// items a list has. A machine keeps its stack between runs, so it only grows
+// for the first run of a deep program.
 type Stack struct {
-	Values  [1024]float64
-	pointer int
+	Values []Object
 }
 
-func (s *Stack) Push(value float64) {
-	s.Values[s.pointer] = value
-	s.pointer++
+func (s *Stack) Push(value Object) {
+	s.Values = append(s.Values, value)
 }
 
-func (s *Stack) Pop() float64 {
-	value := s.Values[s.pointer-1]
-	s.pointer--
+func (s *Stack) Pop() Object {
+	value := s.Values[len(s.Values)-1]
+	s.Values[len(s.Values)-1] = nil
+	s.Values = s.Values[:len(s.Values)-1]
 	return value
 }
 
+// PopN pops the top n values into a new slice, in the order they were
+// pushed.
+func (s *Stack) PopN(n int) []Object {
+	top := len(s.Values) - n
+	values := make([]Object, n)
+	copy(values, s.Values[top:])
+
+	clear(s.Values[top:])
+	s.Values = s.Values[:top]
+	return values
+}
+
+// reset empties the stack and clears the values it held.
+func (s *Stack) reset() {
+	clear(s.Values)
+	s.Values = s.Values[:0]
+}
+
 func NewStack() Stack {
 	return Stack{
-		Values: [1024]float64{},
+		Values: make([]Object, 0, 64),
 	}
 }
 
+// Vm is a program ready to run, the settings it was compiled with, its
+// constants and its instructions. Running it never modifies it, so a single
+// vm may be run from any number of goroutines at once, each with a machine of
+// its own.
 type Vm struct {
 	Version      uint32
+	NumericMode  NumericMode
+	Precision    uint
+	Scale        int32
+	Rounding     RoundingMode
+	Word         Word
+	Overflow     OverflowMode
+	Angle        AngleMode
+	Params       []string
 	ConstantPool ConstantPool
 	Instructions []Instruction
-	Stack        Stack
+	// Seed seeds the random number generator of new machines.
+	Seed uint64
+	// Locations maps instructions to their source location, only vms built
+	// from a compiler have them.
+	Locations map[int]Location
 }
 
-func (vm Vm) Run() (float64, error) {
-	for _, instruction := range vm.Instructions {
+// Run runs the vm once on a new machine.
+func (vm *Vm) Run() (Object, error) {
+	return vm.RunContext(context.Background())
+}
+
+// RunContext runs the vm once on a new machine like Run, giving up once
+// run_ctx is done.
+func (vm *Vm) RunContext(run_ctx context.Context) (Object, error) {
+	return vm.NewMachine().Run(run_ctx)
+}
+
+// Machine holds the state of running a vm, the stack, the random number
+// generator and the values of the parameters. It can run its vm any number of
+// times but only one goroutine may use it at a time.
+type Machine struct {
+	vm    *Vm
+	Stack Stack
+	Rand  *rand.Rand
+	// Args are the values of the parameters, in the order of Params.
+	Args []Object
+}
+
+func (vm *Vm) NewMachine() *Machine {
+	return &Machine{
+		vm:    vm,
+		Stack: NewStack(),
+		Rand:  new_rand(vm.Seed),
+	}
+}
+
+// Run runs the vm with the arguments bound last, giving up between
+// instructions once run_ctx is done and failing with its error.
+func (m *Machine) Run(run_ctx context.Context) (Object, error) {
+	ctx := m.call_context()
+	// drop what a failed run left behind and let go of the values once done
+	m.Stack.reset()
+	defer m.Stack.reset()
+
+	for ip, instruction := range m.vm.Instructions {
+		select {
+		case <-run_ctx.Done():
+			return nil, run_ctx.Err()
+		default:
+		}
+
 		switch instruction.Op {
 		case OpConstant:
-			constant := vm.ConstantPool.Get(int(instruction.Operands[0]))
-			vm.Stack.Push(constant.(Float64Object).Value)
-		case OpAdd:
-			right := vm.Stack.Pop()
-			left := vm.Stack.Pop()
-
-			vm.Stack.Push(left + right)
-		case OpSub:
-			right := vm.Stack.Pop()
-			left := vm.Stack.Pop()
-
-			vm.Stack.Push(left - right)
-		case OpMul:
-			right := vm.Stack.Pop()
-			left := vm.Stack.Pop()
-
-			vm.Stack.Push(left * right)
-		case OpDiv:
-			right := vm.Stack.Pop()
-			left := vm.Stack.Pop()
-
-			vm.Stack.Push(left / right)
-		case OpMod:
-			right := vm.Stack.Pop()
-			left := vm.Stack.Pop()
-
-			vm.Stack.Push(math.Mod(left, right))
-		case OpPow:
-			right := vm.Stack.Pop()
-			left := vm.Stack.Pop()
-
-			vm.Stack.Push(math.Pow(left, right))
-		case OpCall:
-			fn := *builtin_fns.GetPointer(int(instruction.Operands[0]))
-			args := []Float64Object{}
+			constant := m.vm.ConstantPool.Get(int(instruction.Operands[0]))
+			m.Stack.Push(constant)
+		case OpAdd, OpSub, OpMul, OpDiv, OpMod, OpPow, OpIntDiv, OpAnd, OpOr, OpXor, OpShl, OpShr, OpAddPercent, OpSubPercent, OpPercentOf:
+			right := m.Stack.Pop()
+			left := m.Stack.Pop()
 
-			for i := 0; i < int(instruction.Operands[1]); i++ {
-				value := vm.Stack.Pop()
-				args = append(args, Float64Object{value})
+			result, err := binary_op(ctx, instruction.Op, left, right)
+			if err != nil {
+				return nil, m.vm.locate(ip, err)
+			}
+			m.Stack.Push(result)
+		case OpLoad:
+			index := int(instruction.Operands[0])
+			if index >= len(m.Args) || m.Args[index] == nil {
+				return nil, unbound_err(m.vm.Params[index])
+			}
+			m.Stack.Push(m.Args[index])
+		case OpList:
+			m.Stack.Push(ListObject{m.Stack.PopN(int(instruction.Operands[0]))})
+		case OpIndex:
+			index := m.Stack.Pop()
+			list := m.Stack.Pop()
+
+			result, err := list_index(list, index)
+			if err != nil {
+				return nil, err
 			}
+			m.Stack.Push(result)
+		case OpConvert:
+			target := m.Stack.Pop()
+			unit, ok := target.(QuantityObject)
+			if !ok {
+				return nil, fmt.Errorf("cannot convert to %s, it is not a unit", target)
+			}
+			value := m.Stack.Pop()
 
-			ret, err := fn(args...)
+			result, err := convert_quantity(ctx, value, unit.Unit)
+			if err != nil {
+				return nil, m.vm.locate(ip, err)
+			}
+			m.Stack.Push(result)
+		case OpPercent:
+			result, err := binary_op(ctx, OpDiv, m.Stack.Pop(), big_int(100))
+			if err != nil {
+				return nil, m.vm.locate(ip, err)
+			}
+			m.Stack.Push(result)
+		case OpNot:
+			result, err := bitwise_not(ctx, m.Stack.Pop())
 			if err != nil {
-				return 0, err
+				return nil, err
 			}
-			vm.Stack.Push(ret.Value)
+			result, err = ctx.normalize(result)
+			if err != nil {
+				return nil, err
+			}
+			m.Stack.Push(result)
+		case OpCall:
+			descriptor := builtin_at(int(instruction.Operands[0]))
+			if descriptor == nil {
+				return nil, fmt.Errorf("unknown function pointer %d", instruction.Operands[0])
+			}
+			args := m.Stack.PopN(int(instruction.Operands[1]))
+
+			ret, err := descriptor.Call(ctx, args)
+			if err != nil {
+				return nil, m.vm.locate(ip, err)
+			}
+			m.Stack.Push(ret)
+		}
+	}
+
+	// a lone constant like pi never went through an operation, bring it into
+	// the representation of the numeric mode as well
+	return ctx.normalize(m.Stack.Pop())
+}
+
+func (m *Machine) call_context() *CallContext {
+	return &CallContext{
+		Rand:        m.Rand,
+		NumericMode: m.vm.NumericMode,
+		Precision:   m.vm.Precision,
+		Scale:       m.vm.Scale,
+		Rounding:    m.vm.Rounding,
+		Word:        m.vm.Word,
+		Overflow:    m.vm.Overflow,
+		Angle:       m.vm.Angle,
+	}
+}
+
+// Bind sets the values of the parameters by name, every parameter needs one.
+func (m *Machine) Bind(bindings map[string]float64) error {
+	ctx := m.call_context()
+	m.Args = m.Args[:0]
+	for _, param := range m.vm.Params {
+		value, ok := bindings[param]
+		if !ok {
+			return unbound_err(param)
+		}
+		if err := m.bind(ctx, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BindArgs sets the values of the parameters in the order of Params.
+func (m *Machine) BindArgs(args []float64) error {
+	if len(args) != len(m.vm.Params) {
+		return fmt.Errorf("program expects exactly %d arguments but got %d", len(m.vm.Params), len(args))
+	}
+
+	ctx := m.call_context()
+	m.Args = m.Args[:0]
+	for _, arg := range args {
+		if err := m.bind(ctx, arg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// bind appends the value of the next parameter, the arguments of a failed
+// binding stay short so that running reports the first unbound parameter.
+func (m *Machine) bind(ctx *CallContext, arg float64) error {
+	value, err := ctx.bind(arg)
+	if err != nil {
+		return err
+	}
+	m.Args = append(m.Args, value)
+
+	return nil
+}
+
+func unbound_err(name string) error {
+	return fmt.Errorf("'%s' is neither a constant nor bound to a value", name)
+}
+
+// impure reports whether the vm calls impure builtins like rand().
+func (vm *Vm) impure() bool {
+	for _, instruction := range vm.Instructions {
+		if instruction.Op != OpCall {
+			continue
+		}
+
+		descriptor := builtin_at(int(instruction.Operands[0]))
+		if descriptor != nil && descriptor.Impure {
+			return true
 		}
 	}
 
-	return vm.Stack.Pop(), nil
+	return false
 }
 
-func NewVm(input []byte) (*Vm, error) {
+// locate adds the source location of an instruction to the dimension errors
+// it fails with.
+func (vm *Vm) locate(instruction int, err error) error {
+	dimension_err, ok := err.(DimensionError)
+	if !ok {
+		return err
+	}
+
+	if location, ok := vm.Locations[instruction]; ok {
+		dimension_err.Location = location
+	}
+
+	return dimension_err
+}
+
+func NewVm(input []byte, opts ...Option) (*Vm, error) {
+	config := NewConfig(opts...)
 	deserializer := NewDeserializer(input)
 	deserialized, err := deserializer.Deserialize()
 	if err != nil {
@@ -98,17 +299,40 @@ func NewVm(input []byte) (*Vm, error) {
 
 	return &Vm{
 		Version:      deserialized.Version,
+		NumericMode:  deserialized.NumericMode,
+		Precision:    deserialized.Precision,
+		Scale:        deserialized.Scale,
+		Rounding:     deserialized.Rounding,
+		Word:         deserialized.Word,
+		Overflow:     deserialized.Overflow,
+		Angle:        deserialized.Angle,
+		Params:       deserialized.Params,
 		ConstantPool: deserialized.ConstantPool,
 		Instructions: deserialized.Instructions,
-		Stack:        NewStack(),
+		Seed:         config.Seed,
 	}, nil
 }
 
-func NewVmFromCompiler(c *Compiler) *Vm {
+func NewVmFromCompiler(c *Compiler, opts ...Option) *Vm {
+	config := NewConfig(opts...)
+
 	return &Vm{
 		Version:      c.Version,
+		NumericMode:  c.NumericMode,
+		Precision:    c.Precision,
+		Scale:        c.Scale,
+		Rounding:     c.Rounding,
+		Word:         c.Word,
+		Overflow:     c.Overflow,
+		Angle:        c.Angle,
+		Params:       c.Params,
 		ConstantPool: c.ConstantPool,
 		Instructions: c.Instructions,
-		Stack:        NewStack(),
+		Seed:         config.Seed,
+		Locations:    c.Locations,
 	}
 }
+
+func new_rand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed))
+}
@@ -0,0 +1,110 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// repeat joins n items made by item with separator.
+func repeat(n int, separator string, item func(i int) string) string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = item(i)
+	}
+
+	return strings.Join(items, separator)
+}
+
+func TestDeepPrograms(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Src      string
+		Expected string
+	}{
+		{
+			Name:     "call with over 1024 computed arguments",
+			Src:      fmt.Sprintf("sum(%s)", repeat(1100, ", ", func(i int) string { return fmt.Sprintf("(%d + 1)", i) })),
+			Expected: "605550",
+		},
+		{
+			Name:     "list with over 1024 computed items",
+			Src:      fmt.Sprintf("sum([%s])", repeat(1100, ", ", func(i int) string { return "(1 + 1)" })),
+			Expected: "2200",
+		},
+		{
+			Name:     "nested lists of computed items",
+			Src:      fmt.Sprintf("[%s][1099]", repeat(1100, ", ", func(i int) string { return fmt.Sprintf("[(%d + 1), 0]", i) })),
+			Expected: "[1100, 0]",
+		},
+		{
+			Name:     "over 512 constants",
+			Src:      repeat(1100, " + ", func(i int) string { return fmt.Sprintf("%d.5", i) }),
+			Expected: "605000",
+		},
+	}
+
+	for _, test := range cases {
+		value, err := Eval(test.Src)
+		if err != nil {
+			t.Errorf("%s: %v", test.Name, err)
+			continue
+		}
+
+		if value.String() != test.Expected {
+			t.Errorf("%s: got %s, want %s", test.Name, value, test.Expected)
+		}
+	}
+}
+
+func TestBuiltinTable(t *testing.T) {
+	seen := map[int]string{}
+	for name, descriptor := range builtin_fns {
+		if other, ok := seen[descriptor.Pointer]; ok {
+			t.Errorf("%s and %s share the pointer %d", name, other, descriptor.Pointer)
+		}
+		seen[descriptor.Pointer] = name
+
+		if got := builtin_at(descriptor.Pointer); got == nil || got.Doc != descriptor.Doc {
+			t.Errorf("builtin_at(%d) does not resolve %s", descriptor.Pointer, name)
+		}
+	}
+
+	if builtin_at(-1) != nil || builtin_at(len(builtin_table)) != nil {
+		t.Error("builtin_at resolves pointers out of range")
+	}
+}
+
+func BenchmarkCall(b *testing.B) {
+	program, err := Compile("max(x, 1) + abs(x)")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	args := []float64{2}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := program.RunWith(context.Background(), Env{}, args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestConvertToNonUnit runs a conversion to a plain number, which the
+// compiler never emits but a crafted archive can hold.
+func TestConvertToNonUnit(t *testing.T) {
+	vm := &Vm{
+		ConstantPool: ConstantPool{Values: []Object{Float64Object{1}, Float64Object{2}}},
+		Instructions: []Instruction{
+			NewInstruction(OpConstant, 0),
+			NewInstruction(OpConstant, 1),
+			NewInstruction(OpConvert),
+		},
+	}
+
+	_, err := vm.Run()
+	if err == nil || !strings.Contains(err.Error(), "cannot convert to 2, it is not a unit") {
+		t.Errorf("got error %v, want one about the conversion target", err)
+	}
+}